@@ -0,0 +1,161 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the options of the analysis
+var analysisOptions model.AnalysisOptions
+
+// the directory of the png charts, no chart is rendered if empty
+var chartsPath string
+
+// analyseCmd represents the analyse command
+var analyseCmd = &cobra.Command{
+	Use:     "analyse [flags] dataset-path",
+	Aliases: []string{"analyze"},
+	Short:   "A subcommand to analyse the boxes and classes of the dataset",
+	Long: `A subcommand to analyse the boxes and classes of the dataset: the
+histograms of box width, height, aspect ratio and area of every class,
+the COCO small/medium/large buckets, the distribution of objects per
+image, the heatmap of box centers and the co-occurrence matrix of the
+classes. The analysis is printed as tables, json or yaml, and rendered
+as png charts with --charts.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return AnalyseDataset(iFormat, datasetPath, reportOutput, chartsPath, analysisOptions)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(analyseCmd)
+
+	analyseCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the dataset, detected from the dataset-path if not specified")
+	analyseCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "the output style, table, json or yaml")
+	analyseCmd.Flags().IntVar(&analysisOptions.Bins, "bins", 10, "the number of bins of the histograms")
+	analyseCmd.Flags().IntVar(&analysisOptions.HeatmapSize, "heatmap-size", 10, "the number of cells of each side of the box center heatmap")
+	analyseCmd.Flags().StringVar(&chartsPath, "charts", "", "the directory to render the png charts in")
+}
+
+// AnalyseDataset reads the dataset, prints its analysis and renders the charts
+func AnalyseDataset(iFormat string, datasetPath string, output string, chartsPath string, options model.AnalysisOptions) error {
+	dataset, _, err := readDataset(iFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	analysis := datasets.Analyse(dataset, options)
+	categories := datasets.CategoryNames(dataset)
+
+	writeTable := func(w io.Writer) error {
+		return writeAnalysisTable(w, &analysis, categories)
+	}
+	if err := writeReport(rootCmd.OutOrStdout(), output, &analysis, writeTable); err != nil {
+		return err
+	}
+
+	if chartsPath != "" {
+		if err := datasets.WriteCharts(&analysis, dataset, chartsPath); err != nil {
+			return outputError(err)
+		}
+	}
+	return nil
+}
+
+func writeAnalysisTable(w io.Writer, analysis *model.DatasetAnalysis, categories []string) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "CATEGORY\tBOXES\tWIDTH\tHEIGHT\tASPECT RATIO\tAREA\tSMALL\tMEDIUM\tLARGE\n")
+	rows := append([]model.BoxStatistics{analysis.All}, analysis.Categories...)
+	rows[0].Category = "(all)"
+	for _, statistics := range rows {
+		fmt.Fprintf(table, "%v\t%v\t%.1f\t%.1f\t%.2f\t%.1f\t%v\t%v\t%v\n",
+			statistics.Category, statistics.Boxes,
+			statistics.Width.Mean, statistics.Height.Mean, statistics.AspectRatio.Mean, statistics.Area.Mean,
+			statistics.Sizes.Small, statistics.Sizes.Medium, statistics.Sizes.Large)
+	}
+	fmt.Fprintf(table, "(the means of the box metrics in pixels)\n")
+
+	histogramRows := rows[:1]
+	if verbose {
+		histogramRows = rows
+	}
+	for _, statistics := range histogramRows {
+		metrics := []struct {
+			name       string
+			statistics model.MetricStatistics
+		}{
+			{"width", statistics.Width},
+			{"height", statistics.Height},
+			{"aspect ratio", statistics.AspectRatio},
+			{"area", statistics.Area},
+		}
+		for _, metric := range metrics {
+			fmt.Fprintf(table, "\n%v %v\tCOUNT\t\n", strings.ToUpper(metric.name), statistics.Category)
+			for _, bin := range metric.statistics.Histogram {
+				fmt.Fprintf(table, "[%.4g, %.4g]\t%v\t%v\n", bin.Min, bin.Max, bin.Count, histogramBar(bin.Count, statistics.Boxes))
+			}
+		}
+	}
+
+	fmt.Fprintf(table, "\nOBJECTS\tIMAGES\n")
+	for _, objects := range analysis.ObjectsPerImage {
+		fmt.Fprintf(table, "%v\t%v\n", objects.Objects, objects.Images)
+	}
+
+	fmt.Fprintf(table, "\nBOX CENTERS (rows top to bottom, columns left to right)\n")
+	for _, row := range analysis.CenterHeatmap {
+		fmt.Fprintf(table, "%v\n", joinInts(row, "\t"))
+	}
+
+	fmt.Fprintf(table, "\nCO-OCCURRENCE\t%v\n", strings.Join(categories, "\t"))
+	for index, row := range analysis.CoOccurrence {
+		fmt.Fprintf(table, "%v\t%v\n", categories[index], joinInts(row, "\t"))
+	}
+
+	return table.Flush()
+}
+
+// histogramBar draws the count as a bar of at most 40 characters
+func histogramBar(count int, total int) string {
+	if total == 0 {
+		return ""
+	}
+	return strings.Repeat("#", count*40/total)
+}
+
+func joinInts(values []int, sep string) string {
+	texts := make([]string, len(values))
+	for index, value := range values {
+		texts[index] = fmt.Sprint(value)
+	}
+	return strings.Join(texts, sep)
+}
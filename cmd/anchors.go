@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the options of the anchor estimation
+var anchorOptions model.AnchorOptions
+
+// the snippet to print instead of the report, cfg or yaml
+var anchorExport string
+
+// anchorsCmd represents the analyse anchors command
+var anchorsCmd = &cobra.Command{
+	Use:   "anchors [flags] dataset-path",
+	Short: "A subcommand to estimate the anchor boxes of the dataset",
+	Long: `A subcommand to estimate the anchor boxes of the dataset by k-means
+with the 1-IoU distance over the box sizes, scaled as the images are
+resized to --img-size on the longest side, and optionally refined by a
+genetic algorithm for --generations. The anchors are reported with the
+mean best IoU and the best possible recall, the share of the boxes some
+anchor fits within --threshold times on both sides, or exported as the
+snippet of a darknet cfg or an ultralytics yaml with --export.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return EstimateAnchors(iFormat, datasetPath, reportOutput, anchorExport, anchorOptions)
+	},
+}
+
+func init() {
+	analyseCmd.AddCommand(anchorsCmd)
+
+	anchorsCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the dataset, detected from the dataset-path if not specified")
+	anchorsCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "the output style, table, json or yaml")
+	anchorsCmd.Flags().IntVarP(&anchorOptions.Clusters, "clusters", "k", 9, "the number of anchors")
+	anchorsCmd.Flags().IntVar(&anchorOptions.ImageSize, "img-size", 640, "the training image size, 0 to keep the box sizes of the original images")
+	anchorsCmd.Flags().IntVar(&anchorOptions.Generations, "generations", 0, "the generations of the genetic refinement, 0 to skip it")
+	anchorsCmd.Flags().Float64Var(&anchorOptions.Threshold, "threshold", 4, "the largest ratio between the sides of a box and a fitting anchor")
+	anchorsCmd.Flags().Int64Var(&anchorOptions.Seed, "seed", 0, "the random seed of the clustering")
+	anchorsCmd.Flags().StringVar(&anchorExport, "export", "", "print the anchors as a snippet of a darknet cfg or an ultralytics yaml, cfg or yaml")
+}
+
+// EstimateAnchors reads the dataset and prints its anchors
+func EstimateAnchors(iFormat string, datasetPath string, output string, export string, options model.AnchorOptions) error {
+	if export != "" && export != "cfg" && export != "yaml" {
+		return usageError(fmt.Errorf("unknown export [%v], cfg or yaml", export))
+	}
+
+	dataset, _, err := readDataset(iFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	result, err := datasets.EstimateAnchors(dataset, options)
+	if err != nil {
+		// the options do not fit the boxes of the dataset
+		return inputError(err)
+	}
+
+	switch export {
+	case "cfg":
+		_, err = fmt.Fprint(rootCmd.OutOrStdout(), result.DarknetCfg())
+	case "yaml":
+		_, err = fmt.Fprint(rootCmd.OutOrStdout(), result.UltralyticsYAML())
+	default:
+		writeTable := func(w io.Writer) error {
+			return writeAnchorsTable(w, &result)
+		}
+		return writeReport(rootCmd.OutOrStdout(), output, &result, writeTable)
+	}
+	if err != nil {
+		return failureError(err)
+	}
+	return nil
+}
+
+func writeAnchorsTable(w io.Writer, result *model.AnchorResult) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "ANCHOR\tWIDTH\tHEIGHT\n")
+	for index, anchor := range result.Anchors {
+		fmt.Fprintf(table, "%v\t%.1f\t%.1f\n", index+1, anchor.Width, anchor.Height)
+	}
+
+	fmt.Fprintf(table, "\nBoxes:\t%v\n", result.Boxes)
+	fmt.Fprintf(table, "Mean best IoU:\t%.4f\n", result.MeanBestIoU)
+	fmt.Fprintf(table, "Best possible recall:\t%.4f\n", result.BestPossibleRecall)
+	fmt.Fprintf(table, "Anchors per box:\t%.2f\n", result.AnchorsPerBox)
+	return table.Flush()
+}
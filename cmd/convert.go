@@ -25,28 +25,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/smslit/datasetgo/datasets"
 	"github.com/smslit/datasetgo/model"
 	"github.com/spf13/cobra"
 )
 
-// Define the dataset format enums
-type DatasetFormat string
-
-const (
-	COCO      DatasetFormat = "coco"
-	PascalVOC DatasetFormat = "voc"
-	CreateML  DatasetFormat = "createml"
-)
-
 // the format of the source dataset
-var iFormat DatasetFormat
+var iFormat string
 
 // the format of the outputed dataset
-var oFormat DatasetFormat
+var oFormat string
 
 // the path of the outputed dataset, a file or directory
 var oDatasetPath string
@@ -54,150 +44,163 @@ var oDatasetPath string
 // the path of the source dataset, a file or directory
 var datasetPath string
 
+// how the images are placed in the layout of the outputed dataset
+var imagesMode string
+
 // convertCmd represents the convert command
 var convertCmd = &cobra.Command{
 	Use:   "convert [flags] dataset-path",
 	Short: "A subcommand to convert the dataset format",
-	Long: `A subcommand to convert the dataset format. The supported 
-formats as follows:
-- coco: COCO
-- voc: PascalVOC
-- createml: Create ML(apple)`,
-	Args: func(cmd *cobra.Command, args []string) error {
-		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
-			return err
-		}
-
-		// check the path if exist
-		if _, err := os.Stat(args[0]); err == nil || os.IsExist(err) {
-			datasetPath = args[0]
-			return nil
-		}
-
-		return errors.New("the dataset-path does not exist")
+	Long:  convertUsage(),
+	Args:  datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ConvertDataset(iFormat, oFormat, datasetPath, oDatasetPath, remapPath, dropEmptyImages, imagesMode)
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-
-		switch oFormat {
-		case PascalVOC:
-			ConvertToPascalVOC(iFormat, oFormat, datasetPath, oDatasetPath)
+}
 
-		case COCO:
-			ConvertToCOCO(iFormat, oFormat, datasetPath, oDatasetPath)
+// datasetPathArgs accepts exactly one existing dataset-path argument
+func datasetPathArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+		return err
+	}
 
-		case CreateML:
-			ConvertToCreateML(iFormat, oFormat, datasetPath, oDatasetPath)
+	// check the path if exist
+	if _, err := os.Stat(args[0]); err == nil || os.IsExist(err) {
+		datasetPath = args[0]
+		return nil
+	}
 
-		default:
-			rootCmd.PrintErrln(errors.New("the specified format is not supported"))
-		}
-	},
+	return inputError(errors.New("the dataset-path does not exist"))
 }
 
 func init() {
 	rootCmd.AddCommand(convertCmd)
 
-	convertCmd.Flags().StringVarP((*string)(&iFormat), "input-format", "i", "", "the format of the source dataset")
-	convertCmd.MarkFlagRequired("iutput-format")
-	convertCmd.Flags().StringVarP((*string)(&oFormat), "output-format", "o", "", "the format of the outputed dataset")
+	convertCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the source dataset, detected from the dataset-path if not specified")
+	convertCmd.Flags().StringVarP(&oFormat, "output-format", "o", "", "the format of the outputed dataset")
 	convertCmd.MarkFlagRequired("output-format")
-	convertCmd.Flags().StringVarP((*string)(&oDatasetPath), "output-path", "p", "", "the path of the outputed dataset, a file or directory")
+	convertCmd.Flags().StringVarP(&oDatasetPath, "output-path", "p", "", "the path of the outputed dataset, a file or directory")
+	convertCmd.Flags().StringVar(&remapPath, "remap", "", "the yaml or json file of the category remap applied before writing, see the remap command")
+	convertCmd.Flags().BoolVar(&dropEmptyImages, "drop-empty", false, "remove the images left without annotations by the remap")
+	convertCmd.Flags().StringVar(&imagesMode, "images", model.ImagesNone, "place the images in the layout of the output format, e.g. JPEGImages/ of voc, by copy, symlink or hardlink, none to leave them")
 }
 
-func ConvertToPascalVOC(iFormat DatasetFormat, oFormat DatasetFormat, datasetPath string, oDatasetPath string) {
-	var err error
-	var annotations model.VOCAnnotations
+// ConvertDataset reads the source dataset into the common dataset model and
+// writes it out in the output format, the categories are remapped by the
+// remap file if any and the images are placed in the layout of the output
+// format unless images is none
+func ConvertDataset(iFormat string, oFormat string, datasetPath string, oDatasetPath string, remapPath string, dropEmptyImages bool, images string) error {
+	outputFormat, err := datasets.LookupOutputFormat(oFormat, nil)
+	if err != nil {
+		return usageError(err)
+	}
+	if err := checkImagesMode(outputFormat, images); err != nil {
+		return err
+	}
 
-	if iFormat == COCO {
-		err = model.ReadVOCAnnotationsFromCOCOFile(&annotations, datasetPath)
-	} else {
-		err = model.ReadVOCAnnotationsFromCreateMLFile(&annotations, datasetPath)
+	var remap *datasets.CategoryRemap
+	if remapPath != "" {
+		if remap, err = datasets.ReadCategoryRemap(remapPath); err != nil {
+			return inputError(err)
+		}
+		remap.DropEmptyImages = remap.DropEmptyImages || dropEmptyImages
 	}
 
+	dataset, _, err := readDataset(iFormat, datasetPath)
 	if err != nil {
-		rootCmd.PrintErrln(err)
-		return
+		return err
+	}
+
+	if remap != nil {
+		report, err := datasets.Remap(dataset, remap)
+		if err != nil {
+			// the remap file does not fit the dataset
+			return inputError(err)
+		}
+		if verbose {
+			if err := writeRemapTable(rootCmd.OutOrStdout(), &report); err != nil {
+				return failureError(err)
+			}
+		}
 	}
 
 	// get an valid output path
 	if oDatasetPath == "" {
-		oDatasetPath = filepath.Dir(datasetPath)
-	} else {
-		if _, err := os.Stat(oDatasetPath); !(err == nil || os.IsExist(err)) {
-			os.MkdirAll(oDatasetPath, os.ModePerm)
-		}
+		oDatasetPath = datasets.DefaultOutputPath(outputFormat, datasetPath)
 	}
 
-	// output the annotations data to xml files
-	if err := model.WriteVOCAnnotationsToFile(&annotations, oDatasetPath); err != nil {
-		rootCmd.PrintErrln(err)
+	if err := model.WriteDatasetWithImages(outputFormat, dataset, oDatasetPath, images); err != nil {
+		return writeError(err)
 	}
+	return nil
 }
 
-func ConvertToCOCO(iFormat DatasetFormat, oFormat DatasetFormat, datasetPath string, oDatasetPath string) {
-	var err error
-	var dataDir string
-	var annotations model.COCOAnnotations
-
-	if iFormat == PascalVOC {
-		dataDir = datasetPath
-		err = model.ReadCOCOAnnotationsFromPascalVOCDir(&annotations, datasetPath)
-	} else {
-		dataDir = filepath.Dir(datasetPath)
-		err = model.ReadCOCOAnnotationsFromCreateMLFile(&annotations, datasetPath)
+// checkImagesMode checks the way to place the images in the layout of the
+// output format before any dataset is read
+func checkImagesMode(outputFormat *model.Format, images string) error {
+	switch images {
+	case model.ImagesNone:
+	case model.ImagesCopy, model.ImagesSymlink, model.ImagesHardlink:
+		if _, ok := outputFormat.Writer.(model.DatasetLayoutWriter); !ok {
+			return usageError(fmt.Errorf("the format %v has no image layout", outputFormat.Name))
+		}
+	default:
+		return usageError(fmt.Errorf("unknown images [%v], copy, symlink, hardlink or none", images))
 	}
+	return nil
+}
 
+// readDataset reads the dataset at the path in the named format, the format
+// is detected from the path when the name is empty
+func readDataset(name string, datasetPath string) (*datasets.Dataset, *datasets.Format, error) {
+	name, err := inputFormatName(name, datasetPath)
 	if err != nil {
-		rootCmd.PrintErrln(err)
-		return
+		return nil, nil, err
 	}
 
-	// get an valid output path
-	if oDatasetPath == "" {
-		nowTimeString := time.Now().Format("20060102150405")
-		oDatasetPath = filepath.Join(dataDir, fmt.Sprintf("_annotations.coco.%v.json", nowTimeString))
-	} else {
-		if pathExt := filepath.Ext(oDatasetPath); pathExt == "" || strings.ToLower(pathExt) != ".json" {
-			rootCmd.PrintErrln(errors.New(oDatasetPath + " is not a valid json file path"))
-			return
-		}
-	}
-
-	// output the valid coco json file
-	if err := model.WriteCOCOAnnotationsToFile(&annotations, oDatasetPath); err != nil {
-		rootCmd.PrintErrln(err)
+	dataset, format, err := datasets.Read(datasetPath, name)
+	if err != nil {
+		return nil, nil, inputError(err)
 	}
+	return dataset, format, nil
 }
 
-func ConvertToCreateML(iFormat DatasetFormat, oFormat DatasetFormat, datasetPath string, oDatasetPath string) {
-	var err error
-	var dataDir string
-	var annotations model.CreateMLAnnotations
-	if iFormat == PascalVOC {
-		dataDir = datasetPath
-		err = model.ReadCreateMLAnnotationsFromPascalVOCDir(&annotations, datasetPath)
-	} else {
-		dataDir = filepath.Dir(datasetPath)
-		err = model.ReadCreateMLAnnotationsFromCOCOFile(&annotations, datasetPath)
+// inputFormatName returns the name of the registered format, or the format
+// detected from the path when the name is empty
+func inputFormatName(name string, datasetPath string) (string, error) {
+	if name != "" {
+		if _, err := datasets.LookupFormat(name); err != nil {
+			return "", usageError(err)
+		}
+		return name, nil
 	}
 
+	format, err := datasets.Detect(datasetPath)
 	if err != nil {
-		rootCmd.PrintErrln(err)
-		return
+		return "", inputError(err)
 	}
-
-	// get an valid output path
-	if oDatasetPath == "" {
-		nowTimeString := time.Now().Format("20060102150405")
-		oDatasetPath = filepath.Join(dataDir, fmt.Sprintf("_annotations.createml.%v.json", nowTimeString))
-	} else {
-		if pathExt := filepath.Ext(oDatasetPath); pathExt == "" || strings.ToLower(pathExt) != ".json" {
-			rootCmd.PrintErrln(errors.New(oDatasetPath + " is not a valid json file path"))
-			return
-		}
+	if verbose {
+		rootCmd.Printf("detected the format of %v: %v\n", datasetPath, format.Name)
 	}
+	return format.Name, nil
+}
 
-	if err := model.WriteCreateMLAnnotationsToFile(&annotations, oDatasetPath); err != nil {
-		rootCmd.PrintErrln(err)
+// convertUsage returns the long help message of the convert command
+func convertUsage() string {
+	return `A subcommand to convert the dataset format. The supported 
+formats as follows:
+` + formatsUsage()
+}
+
+// formatsUsage lists the registered formats for the help message
+func formatsUsage() string {
+	var builder strings.Builder
+	for _, format := range datasets.Formats() {
+		builder.WriteString(fmt.Sprintf("- %v: %v", format.Name, format.Description))
+		if len(format.Aliases) > 0 {
+			builder.WriteString(fmt.Sprintf(" (aliases: %v)", strings.Join(format.Aliases, ", ")))
+		}
+		builder.WriteString("\n")
 	}
+	return strings.TrimSuffix(builder.String(), "\n")
 }
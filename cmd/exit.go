@@ -0,0 +1,107 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smslit/datasetgo/model"
+)
+
+// the exit codes of the commands
+const (
+	// an unexpected failure, e.g. the report can not be printed
+	ExitFailure = 1
+
+	// the arguments, flags, formats or options are invalid
+	ExitUsage = 2
+
+	// the dataset or the remap or mapping file can not be read
+	ExitInput = 3
+
+	// the validation found issues at least as severe as --fail-on
+	ExitValidation = 4
+
+	// the output was written in part or not at all
+	ExitPartialOutput = 5
+)
+
+// ExitError is an error ending the command with the exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// exitCode returns the exit code of the error returned by a command, the
+// commands wrap their errors in ExitError so the other errors come from the
+// arguments and flags checked by cobra
+func exitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitUsage
+}
+
+func failureError(err error) error {
+	return &ExitError{Code: ExitFailure, Err: err}
+}
+
+func usageError(err error) error {
+	return &ExitError{Code: ExitUsage, Err: err}
+}
+
+func inputError(err error) error {
+	return &ExitError{Code: ExitInput, Err: err}
+}
+
+func outputError(err error) error {
+	return &ExitError{Code: ExitPartialOutput, Err: err}
+}
+
+// writeError returns the error of writing a dataset, an input error when
+// the dataset refers to missing images or records
+func writeError(err error) error {
+	var missingImage *model.MissingImageError
+	var danglingReference *model.DanglingReferenceError
+	if errors.As(err, &missingImage) || errors.As(err, &danglingReference) {
+		return inputError(err)
+	}
+	return outputError(err)
+}
+
+// validationError is returned when the dataset fails the validation
+func validationError(errors int, warnings int) error {
+	return &ExitError{
+		Code: ExitValidation,
+		Err:  fmt.Errorf("the validation failed with %v errors and %v warnings", errors, warnings),
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/smslit/datasetgo/model"
+)
+
+func TestExitCode(t *testing.T) {
+	missingImage := &model.MissingImageError{Path: "a.jpg", Err: fs.ErrNotExist}
+	danglingReference := &model.DanglingReferenceError{Kind: model.ReferenceImage, ID: 2, AnnotationID: 1}
+	failed := errors.New("no space left on device")
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"plain", failed, ExitUsage},
+		{"failure", failureError(failed), ExitFailure},
+		{"usage", usageError(failed), ExitUsage},
+		{"input", inputError(failed), ExitInput},
+		{"wrapped input", fmt.Errorf("reading a.json: %w", inputError(failed)), ExitInput},
+		{"input of multi error", inputError(model.MultiError{failed, missingImage}), ExitInput},
+		{"output", outputError(failed), ExitPartialOutput},
+		{"validation", validationError(1, 2), ExitValidation},
+		{"write", writeError(failed), ExitPartialOutput},
+		{"write missing image", writeError(missingImage), ExitInput},
+		{"write wrapped missing image", writeError(fmt.Errorf("placing the images: %w", missingImage)), ExitInput},
+		{"write dangling reference", writeError(danglingReference), ExitInput},
+		{"write multi error", writeError(model.MultiError{failed, failed}), ExitPartialOutput},
+		{"write multi error with missing image", writeError(model.MultiError{failed, missingImage}), ExitInput},
+		{"write wrapped multi error", writeError(fmt.Errorf("writing: %w", model.MultiError{failed, danglingReference})), ExitInput},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := exitCode(test.err); code != test.code {
+				t.Errorf("exit code of %v = %v, want %v", test.err, code, test.code)
+			}
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	defer func() {
+		exit = os.Exit
+		rootCmd.SetArgs(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetOut(nil)
+		// cobra keeps the flags parsed by the last run
+		iFormat, reportOutput, remapPath = "", "table", ""
+	}()
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"missing argument", []string{"info"}, ExitUsage},
+		{"unknown flag", []string{"info", "--unknown", "a.json"}, ExitUsage},
+		{"unknown output", []string{"info", "-o", "xml", filepath.Join("..", "model", "testdata", "coco.json")}, ExitUsage},
+		{"missing dataset", []string{"info", "missing.json"}, ExitInput},
+		{"missing merged dataset", []string{"merge", "-o", "coco", "-p", "merged.json", filepath.Join("..", "model", "testdata", "coco.json"), "missing.json"}, ExitInput},
+		{"unknown format", []string{"convert", "-i", "xml", "-o", "coco", filepath.Join("..", "model", "testdata", "coco.json")}, ExitUsage},
+		{"missing remap flag", []string{"remap", filepath.Join("..", "model", "testdata", "coco.json")}, ExitUsage},
+		{"missing remap", []string{"remap", "--mapping", "missing.yaml", filepath.Join("..", "model", "testdata", "coco.json")}, ExitInput},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code := -1
+			exit = func(c int) {
+				code = c
+			}
+			rootCmd.SetArgs(test.args)
+			rootCmd.SetErr(ioutil.Discard)
+			rootCmd.SetOut(ioutil.Discard)
+
+			Execute()
+			if code != test.code {
+				t.Errorf("exit code of %v = %v, want %v", test.args, code, test.code)
+			}
+		})
+	}
+}
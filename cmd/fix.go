@@ -0,0 +1,135 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the options of the fix
+var fixOptions model.FixOptions
+
+// the path of the json changelog, no changelog file is written if empty
+var changelogPath string
+
+// only print the changes without writing the fixed dataset
+var dryRun bool
+
+// fixCmd represents the fix command
+var fixCmd = &cobra.Command{
+	Use:   "fix [flags] dataset-path",
+	Short: "A subcommand to repair the common defects of the annotations",
+	Long: `A subcommand to repair the common defects of the annotations found by
+the validate command: the duplicate IDs are renumbered, the inverted
+boxes swapped and the boxes clipped to the images, the boxes smaller
+than --min-box-size removed, and the annotations of missing images or
+categories removed. Unless --skip-images, the image sizes are corrected
+from the image headers and the images whose files are missing removed.
+
+The fixed dataset is written in the output format, every change is
+printed and recorded in the json file of --changelog for the audit.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fixOptions.CheckImages = !skipImages
+		return FixDataset(iFormat, oFormat, datasetPath, oDatasetPath, changelogPath, dryRun, fixOptions)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+
+	fixCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the source dataset, detected from the dataset-path if not specified")
+	fixCmd.Flags().StringVarP(&oFormat, "output-format", "o", "", "the format of the fixed dataset, the format of the source dataset if not specified")
+	fixCmd.Flags().StringVarP(&oDatasetPath, "output-path", "p", "", "the path of the fixed dataset, a file or directory next to the source dataset if not specified")
+	fixCmd.Flags().StringVar(&changelogPath, "changelog", "", "the path of the json changelog")
+	fixCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the changes without writing the fixed dataset")
+	fixCmd.Flags().BoolVar(&skipImages, "skip-images", false, "do not check the image files")
+	fixCmd.Flags().Float64Var(&fixOptions.MinBoxSize, "min-box-size", 1, "the boxes narrower or shorter than it in pixels after clipping are removed")
+}
+
+// FixDataset reads the source dataset, repairs it and writes the fixed
+// dataset with the changelog
+func FixDataset(iFormat string, oFormat string, datasetPath string, oDatasetPath string, changelogPath string, dryRun bool, options model.FixOptions) error {
+	dataset, inputFormat, err := readDataset(iFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+	outputFormat, err := datasets.LookupOutputFormat(oFormat, inputFormat)
+	if err != nil {
+		return usageError(err)
+	}
+
+	changelog := datasets.Fix(dataset, options)
+	changelog.Format = inputFormat.Name
+	changelog.Path = datasetPath
+
+	if !dryRun {
+		if oDatasetPath == "" {
+			oDatasetPath = datasets.DefaultOutputPath(outputFormat, datasetPath)
+			if outputFormat.Extension == "" {
+				oDatasetPath = filepath.Join(oDatasetPath, "fixed")
+			}
+		}
+		changelog.Output = oDatasetPath
+
+		if err := outputFormat.Writer.WriteDataset(dataset, oDatasetPath); err != nil {
+			return writeError(err)
+		}
+	}
+
+	if err := writeChangelogTable(rootCmd.OutOrStdout(), &changelog); err != nil {
+		return failureError(err)
+	}
+	if changelogPath != "" {
+		if err := datasets.WriteFixChangelog(&changelog, changelogPath); err != nil {
+			return outputError(err)
+		}
+	}
+	return nil
+}
+
+func writeChangelogTable(w io.Writer, changelog *model.FixChangelog) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "Format:\t%v\n", changelog.Format)
+	fmt.Fprintf(table, "Path:\t%v\n", changelog.Path)
+	if changelog.Output != "" {
+		fmt.Fprintf(table, "Output:\t%v\n", changelog.Output)
+	}
+	fmt.Fprintf(table, "Changes:\t%v\n", len(changelog.Changes))
+
+	if len(changelog.Changes) > 0 {
+		fmt.Fprintf(table, "\nACTION\tMESSAGE\n")
+		for _, change := range changelog.Changes {
+			fmt.Fprintf(table, "%v\t%v\n", change.Action, change.Message)
+		}
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// the output style of the reports, table, json or yaml
+var reportOutput string
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:     "info [flags] dataset-path",
+	Aliases: []string{"list"},
+	Short:   "A subcommand to list the general info of the dataset",
+	Long: `A subcommand to list the general info of the dataset: the numbers of
+images and annotations, the instances and images of every category, the
+distribution of image sizes, the images without annotations and the
+metadata of the format, e.g. the info and licenses of coco or the source
+of voc. The info is printed as a table, json or yaml.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ListDatasetInfo(iFormat, datasetPath, reportOutput)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the dataset, detected from the dataset-path if not specified")
+	infoCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "the output style, table, json or yaml")
+}
+
+// ListDatasetInfo reads the dataset and prints its summary, a coco json is
+// summarized record by record
+func ListDatasetInfo(iFormat string, datasetPath string, output string) error {
+	name, err := inputFormatName(iFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	summary, format, err := datasets.SummarizeFile(datasetPath, name)
+	if err != nil {
+		return inputError(err)
+	}
+	summary.Format = format.Name
+	summary.Path = datasetPath
+
+	writeTable := func(w io.Writer) error {
+		return writeSummaryTable(w, &summary)
+	}
+	return writeReport(rootCmd.OutOrStdout(), output, &summary, writeTable)
+}
+
+// writeReport writes the report as json or yaml, or as a table by writeTable
+func writeReport(w io.Writer, output string, report interface{}, writeTable func(io.Writer) error) error {
+	var err error
+	switch strings.ToLower(output) {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		err = encoder.Encode(report)
+
+	case "yaml", "yml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(report); err == nil {
+			err = encoder.Close()
+		}
+
+	case "table", "":
+		err = writeTable(w)
+
+	default:
+		return usageError(fmt.Errorf("the output %q is not supported, valid outputs: table, json, yaml", output))
+	}
+
+	if err != nil {
+		return failureError(err)
+	}
+	return nil
+}
+
+func writeSummaryTable(w io.Writer, summary *model.DatasetSummary) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "Format:\t%v\n", summary.Format)
+	fmt.Fprintf(table, "Path:\t%v\n", summary.Path)
+	fmt.Fprintf(table, "Images:\t%v\n", summary.Images)
+	fmt.Fprintf(table, "Annotations:\t%v\n", summary.Annotations)
+	fmt.Fprintf(table, "Empty images:\t%v\n", len(summary.EmptyImages))
+
+	fmt.Fprintf(table, "\nID\tCATEGORY\tINSTANCES\tIMAGES\n")
+	for _, category := range summary.Categories {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", category.ID, category.Name, category.Instances, category.Images)
+	}
+
+	fmt.Fprintf(table, "\nWIDTH\tHEIGHT\tIMAGES\n")
+	for _, size := range summary.ImageSizes {
+		fmt.Fprintf(table, "%v\t%v\t%v\n", size.Width, size.Height, size.Images)
+	}
+
+	if len(summary.Info) > 0 || len(summary.Metadata) > 0 {
+		fmt.Fprintf(table, "\nMETADATA\tVALUE\n")
+		for _, metadata := range []map[string]string{summary.Info, summary.Metadata} {
+			keys := make([]string, 0, len(metadata))
+			for key := range metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(table, "%v\t%v\n", key, metadata[key])
+			}
+		}
+	}
+
+	if len(summary.Licenses) > 0 {
+		fmt.Fprintf(table, "\nLICENSE\tNAME\tURL\n")
+		for _, license := range summary.Licenses {
+			fmt.Fprintf(table, "%v\t%v\t%v\n", license.ID, license.Name, license.URL)
+		}
+	}
+
+	if len(summary.EmptyImages) > 0 && verbose {
+		fmt.Fprintf(table, "\nEMPTY IMAGE\n")
+		for _, fileName := range summary.EmptyImages {
+			fmt.Fprintf(table, "%v\n", fileName)
+		}
+	}
+
+	return table.Flush()
+}
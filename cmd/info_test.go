@@ -0,0 +1,123 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/smslit/datasetgo/model"
+	"gopkg.in/yaml.v3"
+)
+
+func newInfoTestSummary() model.DatasetSummary {
+	return model.DatasetSummary{
+		Format:      "coco",
+		Path:        "a.json",
+		Images:      2,
+		Annotations: 3,
+		Categories:  []model.CategorySummary{{ID: 1, Name: "car", Instances: 1, Images: 1}, {ID: 2, Name: "dog", Instances: 2, Images: 2}},
+		ImageSizes:  []model.ImageSizeSummary{{Width: 100, Height: 80, Images: 2}},
+		EmptyImages: []string{"c.jpg"},
+		Info:        map[string]string{"year": "2022", "version": "1"},
+		Licenses:    []model.LicenseSummary{{ID: 1, Name: "test", URL: "http://a"}},
+		Metadata:    map[string]string{"database": "datasetgo"},
+	}
+}
+
+func TestWriteSummaryTable(t *testing.T) {
+	summary := newInfoTestSummary()
+	var table bytes.Buffer
+	if err := writeSummaryTable(&table, &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	// the info and then the metadata by their keys
+	want := `Format:        coco
+Path:          a.json
+Images:        2
+Annotations:   3
+Empty images:  1
+
+ID  CATEGORY  INSTANCES  IMAGES
+1   car       1          1
+2   dog       2          2
+
+WIDTH  HEIGHT  IMAGES
+100    80      2
+
+METADATA  VALUE
+version   1
+year      2022
+database  datasetgo
+
+LICENSE  NAME  URL
+1        test  http://a
+`
+	if table.String() != want {
+		t.Errorf("table =\n%v\nwant\n%v", table.String(), want)
+	}
+
+	// the empty images are listed when verbose
+	verbose = true
+	defer func() { verbose = false }()
+	table.Reset()
+	if err := writeSummaryTable(&table, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(table.String(), "\nEMPTY IMAGE\nc.jpg\n") {
+		t.Errorf("verbose table =\n%v\nwant the empty images listed", table.String())
+	}
+}
+
+func TestWriteSummaryReport(t *testing.T) {
+	summary := newInfoTestSummary()
+	decoders := map[string]func(data []byte, v interface{}) error{
+		"json": json.Unmarshal,
+		"yaml": yaml.Unmarshal,
+		"yml":  yaml.Unmarshal,
+	}
+	for output, decode := range decoders {
+		var report bytes.Buffer
+		if err := writeReport(&report, output, &summary, nil); err != nil {
+			t.Fatal(err)
+		}
+		var decoded model.DatasetSummary
+		if err := decode(report.Bytes(), &decoded); err != nil {
+			t.Fatalf("decoding the %v report: %v", output, err)
+		}
+		if !reflect.DeepEqual(decoded, summary) {
+			t.Errorf("%v report = %+v, want %+v", output, decoded, summary)
+		}
+	}
+
+	var report bytes.Buffer
+	if err := writeReport(&report, "JSON", &summary, nil); err != nil || !strings.Contains(report.String(), `"empty_images": [`) {
+		t.Errorf("JSON report = %v, %v, want the json keys", report.String(), err)
+	}
+	if err := writeReport(&report, "xml", &summary, nil); err == nil || !strings.Contains(err.Error(), `the output "xml" is not supported`) {
+		t.Errorf("error of the xml report = %v, want it unsupported", err)
+	}
+}
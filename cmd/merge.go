@@ -0,0 +1,189 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the formats of the source datasets, one for all or one for each
+var mergeFormats []string
+
+// the path of the category mapping file
+var mappingPath string
+
+// the way to resolve the same file name in several sources
+var collision string
+
+// how the images are placed under their merged names
+var mergeImages string
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge [flags] dataset-path dataset-path...",
+	Short: "A subcommand to merge several datasets into one",
+	Long: `A subcommand to merge several datasets, possibly in different formats,
+into one dataset in the output format. The categories with the same name
+become one, the names can be changed by the yaml or json object of
+--mapping first, e.g. {"automobile": "car"}. The images and annotations
+get new IDs, and an image file name already taken by an earlier dataset
+gets the dataset name as prefix or directory by --collision. The images
+are copied or linked by --images into the layout of the output format
+under the merged names, with none they are left in the sources and the
+merged annotations refer to files which do not exist. The images,
+annotations, renamed images and instances of every category each
+dataset contributes are printed.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(2)(cmd, args); err != nil {
+			return err
+		}
+		for _, arg := range args {
+			if _, err := os.Stat(arg); err != nil {
+				return inputError(errors.New("the dataset-path " + arg + " does not exist"))
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return MergeDatasets(mergeFormats, oFormat, args, oDatasetPath, mappingPath, collision, mergeImages)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().StringSliceVarP(&mergeFormats, "input-format", "i", nil, "the formats of the source datasets, one for all or one for each, detected from the dataset-paths if not specified")
+	mergeCmd.Flags().StringVarP(&oFormat, "output-format", "o", "", "the format of the merged dataset")
+	mergeCmd.MarkFlagRequired("output-format")
+	mergeCmd.Flags().StringVarP(&oDatasetPath, "output-path", "p", "", "the path of the merged dataset, a file or directory next to the first source dataset if not specified")
+	mergeCmd.Flags().StringVar(&mappingPath, "mapping", "", "the yaml or json file mapping the source category names to the merged names")
+	mergeCmd.Flags().StringVar(&collision, "collision", model.CollisionPrefix, "how to rename the same image file name of a later dataset, prefix or subdir")
+	mergeCmd.Flags().StringVar(&mergeImages, "images", model.ImagesCopy, "place the images under the merged names in the layout of the output format by copy, symlink or hardlink, none to leave them in the sources")
+}
+
+// MergeDatasets reads the source datasets, merges them and writes the merged
+// dataset in the output format, the images are placed under the merged names
+// unless images is none
+func MergeDatasets(iFormats []string, oFormat string, datasetPaths []string, oDatasetPath string, mappingPath string, collision string, images string) error {
+	if len(iFormats) > 1 && len(iFormats) != len(datasetPaths) {
+		return usageError(fmt.Errorf("got %v input formats for %v datasets", len(iFormats), len(datasetPaths)))
+	}
+	if collision != model.CollisionPrefix && collision != model.CollisionSubdir {
+		return usageError(fmt.Errorf("unknown collision [%v], %v or %v", collision, model.CollisionPrefix, model.CollisionSubdir))
+	}
+
+	outputFormat, err := datasets.LookupOutputFormat(oFormat, nil)
+	if err != nil {
+		return usageError(err)
+	}
+	if err := checkImagesMode(outputFormat, images); err != nil {
+		return err
+	}
+
+	options := datasets.MergeOptions{Collision: collision}
+	if mappingPath != "" {
+		if options.Mapping, err = datasets.ReadCategoryMapping(mappingPath); err != nil {
+			return inputError(err)
+		}
+	}
+
+	sources := make([]datasets.MergeSource, len(datasetPaths))
+	for index, datasetPath := range datasetPaths {
+		iFormat := ""
+		if len(iFormats) == 1 {
+			iFormat = iFormats[0]
+		} else if len(iFormats) > 1 {
+			iFormat = iFormats[index]
+		}
+
+		dataset, format, err := readDataset(iFormat, datasetPath)
+		if err != nil {
+			return &ExitError{Code: exitCode(err), Err: fmt.Errorf("%v reading... %w", datasetPath, err)}
+		}
+
+		base := filepath.Base(datasetPath)
+		sources[index] = datasets.MergeSource{
+			Name:    strings.TrimSuffix(base, filepath.Ext(base)),
+			Format:  format.Name,
+			Path:    datasetPath,
+			Dataset: dataset,
+		}
+	}
+
+	merged, report, err := datasets.Merge(sources, options)
+	if err != nil {
+		return inputError(err)
+	}
+
+	if oDatasetPath == "" {
+		oDatasetPath = datasets.DefaultOutputPath(outputFormat, datasetPaths[0])
+		if outputFormat.Extension == "" {
+			oDatasetPath = filepath.Join(oDatasetPath, "merged")
+		}
+	}
+	if err := model.WriteDatasetWithNamedImages(outputFormat, merged, oDatasetPath, images); err != nil {
+		return writeError(err)
+	}
+	if images == model.ImagesNone {
+		rootCmd.PrintErrln("the images are left in the sources, the merged annotations refer to files which do not exist, place them with --images")
+	}
+
+	if err := writeMergeTable(rootCmd.OutOrStdout(), &report); err != nil {
+		return failureError(err)
+	}
+	return nil
+}
+
+func writeMergeTable(w io.Writer, report *model.MergeReport) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "SOURCE\tFORMAT\tPATH\tIMAGES\tANNOTATIONS\tRENAMED IMAGES\n")
+	for _, source := range report.Sources {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\t%v\n", source.Name, source.Format, source.Path, source.Images, source.Annotations, source.RenamedImages)
+	}
+	fmt.Fprintf(table, "(merged)\t\t\t%v\t%v\t\n", report.Images, report.Annotations)
+
+	fmt.Fprintf(table, "\nCATEGORY")
+	for _, source := range report.Sources {
+		fmt.Fprintf(table, "\t%v", strings.ToUpper(source.Name))
+	}
+	fmt.Fprintf(table, "\n")
+	for _, category := range report.Categories {
+		fmt.Fprintf(table, "%v", category)
+		for _, source := range report.Sources {
+			fmt.Fprintf(table, "\t%v", source.Instances[category])
+		}
+		fmt.Fprintf(table, "\n")
+	}
+
+	return table.Flush()
+}
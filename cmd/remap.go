@@ -0,0 +1,147 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the path of the category remap file
+var remapPath string
+
+// remove the images which have no annotation left after the remap
+var dropEmptyImages bool
+
+// remapCmd represents the remap command
+var remapCmd = &cobra.Command{
+	Use:   "remap [flags] dataset-path",
+	Short: "A subcommand to rename, merge and drop the categories of the dataset",
+	Long: `A subcommand to rename, merge and drop the categories of the dataset by
+the yaml or json file of --remap, e.g.
+
+  rename: {Car: car, automobile: car}
+  drop: [bicycle]
+  unmapped: keep
+  ignore_case: true
+  categories:
+    - {name: car, id: 3, supercategory: vehicle}
+
+The categories renamed to the same name are merged, the annotations of
+the dropped categories are removed, and with "unmapped: drop" only the
+renamed or listed categories are kept. The listed categories come first
+in their order with their IDs, e.g. the category IDs of coco, and the
+others follow with the next IDs, or the categories keep their IDs if
+none is listed. A flat object such as {Car: car} only renames, an empty
+name drops the category. An object with any of the keys above or
+drop_empty_images is read as the full remap, so the categories named
+like these keys are renamed under rename, e.g. {rename: {drop: car}}.
+The renamed and dropped categories must be in the dataset. With
+--drop-empty the images left without annotations are removed as well.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --remap is required, either by its name or the deprecated --mapping
+		if remapPath == "" {
+			return usageError(errors.New(`required flag(s) "remap" not set`))
+		}
+		return RemapDataset(iFormat, oFormat, datasetPath, oDatasetPath, remapPath, dropEmptyImages)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(remapCmd)
+
+	remapCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the source dataset, detected from the dataset-path if not specified")
+	remapCmd.Flags().StringVarP(&oFormat, "output-format", "o", "", "the format of the remapped dataset, the format of the source dataset if not specified")
+	remapCmd.Flags().StringVarP(&oDatasetPath, "output-path", "p", "", "the path of the remapped dataset, a file or directory next to the source dataset if not specified")
+	remapCmd.Flags().StringVarP(&remapPath, "remap", "m", "", "the yaml or json file of the category remap, required")
+	remapCmd.Flags().StringVar(&remapPath, "mapping", "", "the yaml or json file of the category remap")
+	remapCmd.Flags().MarkDeprecated("mapping", "use --remap instead")
+	remapCmd.Flags().BoolVar(&dropEmptyImages, "drop-empty", false, "remove the images left without annotations")
+}
+
+// RemapDataset reads the source dataset, remaps its categories and writes the
+// remapped dataset in the output format
+func RemapDataset(iFormat string, oFormat string, datasetPath string, oDatasetPath string, remapPath string, dropEmptyImages bool) error {
+	remap, err := datasets.ReadCategoryRemap(remapPath)
+	if err != nil {
+		return inputError(err)
+	}
+	remap.DropEmptyImages = remap.DropEmptyImages || dropEmptyImages
+
+	dataset, outputFormat, err := readSplitDataset(iFormat, oFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	report, err := datasets.Remap(dataset, remap)
+	if err != nil {
+		// the remap file does not fit the dataset
+		return inputError(err)
+	}
+
+	if oDatasetPath == "" {
+		oDatasetPath = datasets.DefaultOutputPath(outputFormat, datasetPath)
+		if outputFormat.Extension == "" {
+			oDatasetPath = filepath.Join(oDatasetPath, "remapped")
+		}
+	}
+	if err := outputFormat.Writer.WriteDataset(dataset, oDatasetPath); err != nil {
+		return writeError(err)
+	}
+
+	if err := writeRemapTable(rootCmd.OutOrStdout(), &report); err != nil {
+		return failureError(err)
+	}
+	return nil
+}
+
+func writeRemapTable(w io.Writer, report *model.RemapReport) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "ID\tCATEGORY\tNEW ID\tNEW CATEGORY\tINSTANCES\n")
+	for _, change := range report.Changes {
+		if change.TargetID == 0 {
+			fmt.Fprintf(table, "%v\t%v\t-\t(dropped)\t%v\n", change.SourceID, change.Source, change.Instances)
+			continue
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\n", change.SourceID, change.Source, change.TargetID, change.Target, change.Instances)
+	}
+
+	fmt.Fprintf(table, "\nCategories:\t%v\n", len(report.Categories))
+	fmt.Fprintf(table, "Removed annotations:\t%v\n", report.RemovedAnnotations)
+	fmt.Fprintf(table, "Removed images:\t%v\n", len(report.RemovedImages))
+	if verbose {
+		for _, fileName := range report.RemovedImages {
+			fmt.Fprintf(table, "\t%v\n", fileName)
+		}
+	}
+
+	return table.Flush()
+}
@@ -22,13 +22,21 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"errors"
 	"os"
 
+	"github.com/smslit/datasetgo/model"
 	"github.com/spf13/cobra"
 )
 
 var verbose bool
 
+// exit ends the process with the exit code, replaced in the tests
+var exit = os.Exit
+
+// the number of files read at the same time while loading the datasets
+var jobs int
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "datasetgo",
@@ -39,15 +47,37 @@ general info and analyze dataset.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		model.SetLoadJobs(jobs)
+	},
+	// the errors are printed by Execute with their exit codes
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Custom formats registered with model.RegisterFormat before calling it are
+// available to all the subcommands. The process exits with ExitUsage,
+// ExitInput, ExitValidation, ExitPartialOutput or ExitFailure on errors.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	// formats may be registered after the commands are defined
+	convertCmd.Long = convertUsage()
+
+	command, err := rootCmd.ExecuteC()
+	if err == nil {
+		return
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		rootCmd.PrintErrln(err)
+	} else {
+		// the errors of the arguments and flags come from cobra
+		rootCmd.PrintErrln("Error:", err.Error())
+		rootCmd.PrintErrln(command.UsageString())
 	}
+	exit(exitCode(err))
 }
 
 func init() {
@@ -61,4 +91,5 @@ func init() {
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "the number of files read at the same time while loading the datasets or checking the images, the number of CPUs if 0")
 }
@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the names of the splits
+var splitNames []string
+
+// the relative sizes of the splits
+var splitRatios []float64
+
+// the absolute numbers of images in the splits
+var splitCounts []int
+
+// the seed of the random split
+var splitSeed int64
+
+// whether to balance the categories across the splits
+var splitStratify bool
+
+// the regular expression on file names giving the group of an image
+var splitGroupRegex string
+
+// the image field giving the group of an image
+var splitGroupField string
+
+// the number of folds of the cross-validation
+var splitFolds int
+
+// splitCmd represents the split command
+var splitCmd = &cobra.Command{
+	Use:   "split [flags] dataset-path",
+	Short: "A subcommand to split the dataset into train, val and test sets",
+	Long: `A subcommand to split the dataset into train, val and test sets.
+The images are shuffled with the seed, so the same seed always gives
+the same splits on every machine. The splits are written in the output
+format under the output directory, e.g. train.json, val.json and
+test.json for coco, or the ImageSets/Main lists for voc.
+
+With --stratify the instances of every category are spread over the
+splits by the ratios, so rare categories are not left out of a split.
+With --group-regex or --group-field the frames of the same video or
+session never straddle two splits.
+
+With --folds K the images are partitioned into K folds for the k-fold
+cross-validation, fold-N/ under the output directory holds the train
+and val sets of the N-th pair and folds.json records the val images
+of every fold.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := model.SplitOptions{
+			Names:          splitNames,
+			Seed:           splitSeed,
+			Stratify:       splitStratify,
+			GroupAttribute: splitGroupField,
+		}
+		if splitGroupRegex != "" {
+			pattern, err := regexp.Compile(splitGroupRegex)
+			if err != nil {
+				return usageError(err)
+			}
+			options.GroupPattern = pattern
+		}
+		if cmd.Flags().Changed("counts") {
+			options.Counts = splitCounts
+		} else {
+			options.Ratios = splitRatios
+		}
+
+		if splitFolds > 0 {
+			return FoldDataset(iFormat, oFormat, datasetPath, oDatasetPath, splitFolds, options)
+		}
+		return SplitDataset(iFormat, oFormat, datasetPath, oDatasetPath, options)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+
+	splitCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the source dataset, detected from the dataset-path if not specified")
+	splitCmd.Flags().StringVarP(&oFormat, "output-format", "o", "", "the format of the split datasets, the format of the source dataset if not specified")
+	splitCmd.Flags().StringVarP(&oDatasetPath, "output-path", "p", "", "the directory of the split datasets, splits/ next to the source dataset if not specified")
+	splitCmd.Flags().StringSliceVar(&splitNames, "names", []string{"train", "val", "test"}, "the names of the splits")
+	splitCmd.Flags().Float64SliceVar(&splitRatios, "ratios", []float64{0.8, 0.1, 0.1}, "the relative sizes of the splits")
+	splitCmd.Flags().IntSliceVar(&splitCounts, "counts", nil, "the numbers of images in the splits, the rest goes to the first split")
+	splitCmd.Flags().Int64Var(&splitSeed, "seed", 0, "the seed of the random split")
+	splitCmd.Flags().BoolVar(&splitStratify, "stratify", false, "keep the share of instances of every category in each split")
+	splitCmd.Flags().StringVar(&splitGroupRegex, "group-regex", "", "a regular expression on image file names, the images with the same first submatch stay in one split")
+	splitCmd.Flags().IntVar(&splitFolds, "folds", 0, "the number of folds of the k-fold cross-validation, the names, ratios and counts are ignored")
+	splitCmd.Flags().StringVar(&splitGroupField, "group-field", "", "an image field, e.g. video_id of coco images, the images with the same value stay in one split")
+}
+
+// SplitDataset reads the source dataset, partitions it and writes every
+// split in the output format
+func SplitDataset(iFormat string, oFormat string, datasetPath string, oDatasetPath string, options model.SplitOptions) error {
+	dataset, outputFormat, err := readSplitDataset(iFormat, oFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	splits, err := datasets.Split(dataset, options)
+	if err != nil {
+		// the options do not fit the images of the dataset
+		return inputError(err)
+	}
+
+	if oDatasetPath == "" {
+		oDatasetPath = filepath.Join(datasets.DatasetDir(datasetPath), "splits")
+	}
+
+	if err := datasets.WriteSplits(outputFormat, splits, oDatasetPath); err != nil {
+		return writeError(err)
+	}
+
+	if verbose {
+		for _, split := range splits {
+			rootCmd.Printf("%v: %v images, %v annotations\n", split.Name, len(split.Dataset.Images), len(split.Dataset.Annotations))
+		}
+	}
+	return nil
+}
+
+// FoldDataset reads the source dataset, partitions it into the folds and
+// writes the train/val pair of every fold with the manifest
+func FoldDataset(iFormat string, oFormat string, datasetPath string, oDatasetPath string, folds int, options model.SplitOptions) error {
+	dataset, outputFormat, err := readSplitDataset(iFormat, oFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	datasetFolds, err := datasets.Folds(dataset, folds, options)
+	if err != nil {
+		// the options do not fit the images of the dataset
+		return inputError(err)
+	}
+
+	if oDatasetPath == "" {
+		oDatasetPath = filepath.Join(datasets.DatasetDir(datasetPath), "folds")
+	}
+
+	if err := datasets.WriteFolds(outputFormat, datasetFolds, oDatasetPath, datasetPath, options); err != nil {
+		return writeError(err)
+	}
+
+	if verbose {
+		for _, fold := range datasetFolds {
+			rootCmd.Printf("fold-%d: %v train images, %v val images\n", fold.Index, len(fold.Train.Images), len(fold.Val.Images))
+		}
+	}
+	return nil
+}
+
+// readSplitDataset reads the source dataset and returns the format to write
+// it in, the format of the source dataset by default
+func readSplitDataset(iFormat string, oFormat string, datasetPath string) (*datasets.Dataset, *datasets.Format, error) {
+	dataset, inputFormat, err := readDataset(iFormat, datasetPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outputFormat, err := datasets.LookupOutputFormat(oFormat, inputFormat)
+	if err != nil {
+		return nil, nil, usageError(err)
+	}
+	return dataset, outputFormat, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 5km <5km@smslit.cn>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/smslit/datasetgo/datasets"
+	"github.com/smslit/datasetgo/model"
+	"github.com/spf13/cobra"
+)
+
+// the options of the validation
+var validationOptions model.ValidationOptions
+
+// the lowest severity failing the validation, error or warning
+var failOn string
+
+// do not check the image files
+var skipImages bool
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [flags] dataset-path",
+	Short: "A subcommand to check the annotations of the dataset",
+	Long: `A subcommand to check the annotations of the dataset: duplicate IDs,
+annotations referring to missing images or categories, NaN coordinates,
+inverted, zero-area and out-of-bounds boxes, and, unless --skip-images,
+missing image files and sizes disagreeing with the image headers. Every
+issue has a severity of error, warning or info, the report is printed as
+a table, json or yaml, and the command exits with 4 when any issue is at
+least as severe as --fail-on.`,
+	Args: datasetPathArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		validationOptions.CheckImages = !skipImages
+		return ValidateDataset(iFormat, datasetPath, reportOutput, failOn, validationOptions)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&iFormat, "input-format", "i", "", "the format of the dataset, detected from the dataset-path if not specified")
+	validateCmd.Flags().StringVarP(&reportOutput, "output", "o", "table", "the output style, table, json or yaml")
+	validateCmd.Flags().StringVar(&failOn, "fail-on", "error", "the lowest severity failing the validation, error or warning")
+	validateCmd.Flags().BoolVar(&skipImages, "skip-images", false, "do not check the image files")
+}
+
+// ValidateDataset reads the dataset and prints its issues, the error has
+// the code ExitValidation when it does not pass the validation
+func ValidateDataset(iFormat string, datasetPath string, output string, failOn string, options model.ValidationOptions) error {
+	failSeverity, err := datasets.ParseFailSeverity(failOn)
+	if err != nil {
+		return usageError(err)
+	}
+
+	name, err := inputFormatName(iFormat, datasetPath)
+	if err != nil {
+		return err
+	}
+
+	report, format, err := datasets.ValidateFile(datasetPath, name, options)
+	if err != nil {
+		return inputError(err)
+	}
+	report.Format = format.Name
+	report.Path = datasetPath
+
+	writeTable := func(w io.Writer) error {
+		return writeValidationTable(w, &report)
+	}
+	if err := writeReport(rootCmd.OutOrStdout(), output, &report, writeTable); err != nil {
+		return err
+	}
+
+	if !datasets.ValidationPassed(&report, failSeverity) {
+		return validationError(report.Errors, report.Warnings)
+	}
+	return nil
+}
+
+// writeValidationTable writes the errors and warnings, and the infos too
+// when verbose
+func writeValidationTable(w io.Writer, report *model.ValidationReport) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(table, "Format:\t%v\n", report.Format)
+	fmt.Fprintf(table, "Path:\t%v\n", report.Path)
+	fmt.Fprintf(table, "Images:\t%v\n", report.Images)
+	fmt.Fprintf(table, "Annotations:\t%v\n", report.Annotations)
+	fmt.Fprintf(table, "Errors:\t%v\n", report.Errors)
+	fmt.Fprintf(table, "Warnings:\t%v\n", report.Warnings)
+	fmt.Fprintf(table, "Infos:\t%v\n", report.Infos)
+
+	header := false
+	for _, issue := range report.Issues {
+		if issue.Severity == model.SeverityInfo && !verbose {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(table, "\nSEVERITY\tCHECK\tMESSAGE\n")
+			header = true
+		}
+		fmt.Fprintf(table, "%v\t%v\t%v\n", issue.Severity, issue.Check, issue.Message)
+	}
+
+	return table.Flush()
+}
@@ -0,0 +1,256 @@
+// Package datasets is the library API of datasetgo, it reads, writes,
+// converts, validates, fixes, analyses, splits, merges and remaps object
+// detection datasets in process. The functions return their results and
+// errors rather than printing them, and the datasets are read from and written
+// to os paths, file systems or streams in any format registered with
+// RegisterFormat.
+//
+// A COCO file is converted to a CreateML file with
+//
+//	dataset, _, err := datasets.Read("annotations.json", "")
+//	if err != nil {
+//		return err
+//	}
+//	err = datasets.Write(dataset, "createml", "annotations.createml.json")
+package datasets
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/smslit/datasetgo/model"
+)
+
+type (
+	// Dataset is the format-neutral model every format is decoded into
+	Dataset = model.Dataset
+
+	// Format describes a dataset format which can be read and written
+	Format = model.Format
+
+	// MissingImageError is returned when the file of an image does not exist
+	MissingImageError = model.MissingImageError
+
+	// DanglingReferenceError is returned when an annotation refers to an
+	// image or category which does not exist in the dataset
+	DanglingReferenceError = model.DanglingReferenceError
+)
+
+// RegisterFormat adds the format to the formats every function accepts
+func RegisterFormat(format *Format) error {
+	return model.RegisterFormat(format)
+}
+
+// Formats returns all the registered formats sorted by name
+func Formats() []*Format {
+	return model.Formats()
+}
+
+// LookupFormat returns the registered format by its name or one of its aliases
+func LookupFormat(name string) (*Format, error) {
+	return model.LookupFormat(name)
+}
+
+// LookupOutputFormat returns the named format which can be written, or the
+// input format when the name is empty
+func LookupOutputFormat(name string, input *Format) (*Format, error) {
+	if name == "" {
+		if input == nil {
+			return nil, errors.New("the output format is not specified")
+		}
+		if input.Writer == nil {
+			return nil, fmt.Errorf("the format %v can not be written, please specify the output format", input.Name)
+		}
+		return input, nil
+	}
+
+	format, err := model.LookupFormat(name)
+	if err != nil {
+		return nil, err
+	}
+	if format.Writer == nil {
+		return nil, fmt.Errorf("the format %v can not be written", format.Name)
+	}
+	return format, nil
+}
+
+// Detect finds the format of the dataset at the path, a file or directory
+func Detect(path string) (*Format, error) {
+	return model.DetectFormat(path)
+}
+
+// DetectFS finds the format of the dataset at the path of the file system
+func DetectFS(fsys fs.FS, name string) (*Format, error) {
+	return model.DetectFormatFS(fsys, name)
+}
+
+// Read reads the dataset at the path in the named format, the format is
+// detected from the path when the name is empty
+func Read(path string, format string) (*Dataset, *Format, error) {
+	inputFormat, err := lookupOrDetect(format, func() (*Format, error) {
+		return model.DetectFormat(path)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if inputFormat.Reader == nil {
+		return nil, nil, fmt.Errorf("the format %v can not be read", inputFormat.Name)
+	}
+
+	var dataset Dataset
+	if err := inputFormat.Reader.ReadDataset(&dataset, path); err != nil {
+		return nil, nil, err
+	}
+	return &dataset, inputFormat, nil
+}
+
+// ReadFS is like Read but reads the dataset at the path of the file system,
+// the image files of the dataset are read from the file system as well
+func ReadFS(fsys fs.FS, name string, format string) (*Dataset, *Format, error) {
+	inputFormat, err := lookupOrDetect(format, func() (*Format, error) {
+		return model.DetectFormatFS(fsys, name)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if inputFormat.FSReader == nil {
+		return nil, nil, fmt.Errorf("the format %v can not be read from a file system", inputFormat.Name)
+	}
+
+	var dataset Dataset
+	if err := inputFormat.FSReader.ReadDatasetFS(&dataset, fsys, name); err != nil {
+		return nil, nil, err
+	}
+	return &dataset, inputFormat, nil
+}
+
+// Decode decodes the dataset of the named single-file format from r, e.g. a
+// COCO json. The dataset has no image directory.
+func Decode(r io.Reader, format string) (*Dataset, error) {
+	inputFormat, err := model.LookupFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	if inputFormat.Decoder == nil {
+		return nil, fmt.Errorf("the format %v can not be decoded from a stream", inputFormat.Name)
+	}
+
+	var dataset Dataset
+	if err := inputFormat.Decoder.DecodeDataset(&dataset, r); err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+// Write writes the dataset in the named format to the path, a file or
+// directory depending on the format
+func Write(dataset *Dataset, format string, path string) error {
+	outputFormat, err := LookupOutputFormat(format, nil)
+	if err != nil {
+		return err
+	}
+	return outputFormat.Writer.WriteDataset(dataset, path)
+}
+
+// the ways to place the image files of a written dataset
+const (
+	ImagesNone     = model.ImagesNone
+	ImagesCopy     = model.ImagesCopy
+	ImagesSymlink  = model.ImagesSymlink
+	ImagesHardlink = model.ImagesHardlink
+)
+
+// WriteWithImages is like Write but also copies or links the image files
+// into the layout of the format, e.g. JPEGImages/ of voc or images/ of yolo,
+// the file names in the written annotations point to the placed files
+func WriteWithImages(dataset *Dataset, format string, path string, images string) error {
+	outputFormat, err := LookupOutputFormat(format, nil)
+	if err != nil {
+		return err
+	}
+	return model.WriteDatasetWithImages(outputFormat, dataset, path, images)
+}
+
+// WriteWithNamedImages is like WriteWithImages but the images keep their
+// file names under the image directory of the format, e.g. the names given
+// to the images of a merged dataset
+func WriteWithNamedImages(dataset *Dataset, format string, path string, images string) error {
+	outputFormat, err := LookupOutputFormat(format, nil)
+	if err != nil {
+		return err
+	}
+	return model.WriteDatasetWithNamedImages(outputFormat, dataset, path, images)
+}
+
+// Encode encodes the dataset in the named single-file format to w
+func Encode(w io.Writer, dataset *Dataset, format string) error {
+	outputFormat, err := model.LookupFormat(format)
+	if err != nil {
+		return err
+	}
+	if outputFormat.Encoder == nil {
+		return fmt.Errorf("the format %v can not be encoded to a stream", outputFormat.Name)
+	}
+	return outputFormat.Encoder.EncodeDataset(w, dataset)
+}
+
+// lookupOrDetect returns the named format, or the detected one when the
+// name is empty
+func lookupOrDetect(name string, detect func() (*Format, error)) (*Format, error) {
+	if name == "" {
+		return detect()
+	}
+	return model.LookupFormat(name)
+}
+
+// decodeRecordsFile decodes the records of the single-file dataset at the
+// path, it reports false when the format has no record decoder or the path
+// is not a file
+func decodeRecordsFile(format *Format, path string, records model.DatasetRecords) (bool, error) {
+	if format.RecordDecoder == nil {
+		return false, nil
+	}
+	if fileInfo, err := os.Stat(path); err != nil || fileInfo.IsDir() {
+		return false, nil
+	}
+
+	datasetFile, err := os.Open(path)
+	if err != nil {
+		return true, err
+	}
+	defer datasetFile.Close()
+
+	if err := format.RecordDecoder.DecodeRecords(bufio.NewReader(datasetFile), records); err != nil {
+		return true, fmt.Errorf("%v reading... %w", path, err)
+	}
+	return true, nil
+}
+
+// DatasetDir returns the directory of the dataset, the path itself for a
+// directory or the parent directory of a file
+func DatasetDir(path string) string {
+	if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
+		return filepath.Dir(path)
+	}
+	return path
+}
+
+// DefaultOutputPath returns the path next to the dataset at the path where
+// the dataset in the format is written by default, a timestamped file for a
+// single-file format or the directory of the dataset
+func DefaultOutputPath(format *Format, path string) string {
+	dataDir := DatasetDir(path)
+
+	if format.Extension == "" {
+		return dataDir
+	}
+
+	nowTimeString := time.Now().Format("20060102150405")
+	return filepath.Join(dataDir, fmt.Sprintf("_annotations.%v.%v%v", format.Name, nowTimeString, format.Extension))
+}
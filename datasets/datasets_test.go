@@ -0,0 +1,103 @@
+package datasets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// readFile reads the file of the model testdata
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(filepath.Join("..", "model", "testdata", filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReadFS(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, name := range []string{"a.jpg", "a.xml", "b.jpg", "b.xml"} {
+		fsys["voc/"+name] = &fstest.MapFile{Data: readFile(t, "voc/"+name), Mode: 0666}
+	}
+
+	dataset, format, err := ReadFS(fsys, "voc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format.Name != "voc" {
+		t.Errorf("detected format = %v, want voc", format.Name)
+	}
+
+	report := Validate(dataset, ValidationOptions{})
+	if !ValidationPassed(&report, "error") {
+		t.Errorf("validation failed: %+v", report.Issues)
+	}
+
+	var got bytes.Buffer
+	if err := Encode(&got, dataset, "createml"); err != nil {
+		t.Fatal(err)
+	}
+	if want := readFile(t, "voc.createml.golden.json"); !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("createml json:\n%s\nwant:\n%s", got.Bytes(), want)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	source, err := os.Open(filepath.Join("..", "model", "testdata", "coco.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	var got bytes.Buffer
+	result, err := Convert(&got, "createml", source, "coco", ConvertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Format.Name != "createml" || result.Output != "" {
+		t.Errorf("result format = %v, output = %q", result.Format.Name, result.Output)
+	}
+	if want := readFile(t, "coco.createml.golden.json"); !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("createml json:\n%s\nwant:\n%s", got.Bytes(), want)
+	}
+
+	if _, err := Convert(&got, "yolo", strings.NewReader("{}"), "coco", ConvertOptions{}); err == nil {
+		t.Error("converted to a directory format without error")
+	}
+}
+
+func TestSummarizeAndValidateFile(t *testing.T) {
+	// the coco json is decoded record by record, the voc directory is read
+	for _, name := range []string{"coco_instances.json", "voc"} {
+		path := filepath.Join("..", "model", "testdata", name)
+		dataset, _, err := Read(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		summary, format, err := SummarizeFile(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Summarize(dataset); !reflect.DeepEqual(summary, want) {
+			t.Errorf("summary of %v = %+v, want %+v", name, summary, want)
+		}
+
+		options := ValidationOptions{CheckImages: format.Name == "voc"}
+		report, _, err := ValidateFile(path, format.Name, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := Validate(dataset, options); !reflect.DeepEqual(report, want) {
+			t.Errorf("validation of %v = %+v, want %+v", name, report, want)
+		}
+	}
+}
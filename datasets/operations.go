@@ -0,0 +1,324 @@
+package datasets
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+
+	"github.com/smslit/datasetgo/model"
+)
+
+type (
+	Summary           = model.DatasetSummary
+	Severity          = model.Severity
+	ValidationOptions = model.ValidationOptions
+	ValidationReport  = model.ValidationReport
+	FixOptions        = model.FixOptions
+	FixChangelog      = model.FixChangelog
+	AnalysisOptions   = model.AnalysisOptions
+	Analysis          = model.DatasetAnalysis
+	AnchorOptions     = model.AnchorOptions
+	AnchorResult      = model.AnchorResult
+	SplitOptions      = model.SplitOptions
+	DatasetSplit      = model.DatasetSplit
+	DatasetFold       = model.DatasetFold
+	FoldManifest      = model.FoldManifest
+	MergeSource       = model.MergeSource
+	MergeOptions      = model.MergeOptions
+	MergeReport       = model.MergeReport
+	CategoryRemap     = model.CategoryRemap
+	RemapReport       = model.RemapReport
+)
+
+// ConvertOptions are the optional steps of a conversion
+type ConvertOptions struct {
+	// the categories are remapped before writing if not nil
+	Remap *CategoryRemap
+
+	// how the image files are placed in the layout of the output format by
+	// ConvertFile, ImagesNone if empty
+	Images string
+}
+
+// ConvertResult is the dataset converted and how it was converted
+type ConvertResult struct {
+	Dataset *Dataset
+	Format  *Format
+
+	// the path the dataset was written to, empty when it was encoded
+	Output string
+
+	// the report of the remap if any
+	Remap *RemapReport
+}
+
+// Convert decodes the dataset of the input format from r and encodes it in
+// the output format to w, both formats must be single-file ones
+func Convert(w io.Writer, outputFormat string, r io.Reader, inputFormat string, options ConvertOptions) (*ConvertResult, error) {
+	dataset, err := Decode(r, inputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConvertResult{Dataset: dataset}
+	if result.Format, err = model.LookupFormat(outputFormat); err != nil {
+		return nil, err
+	}
+	if err := remapConverted(result, options); err != nil {
+		return nil, err
+	}
+	return result, Encode(w, dataset, outputFormat)
+}
+
+// ConvertFile reads the dataset at the path and writes it in the output
+// format to the output path, next to the dataset if empty. The input format
+// is detected when empty.
+func ConvertFile(path string, inputFormat string, outputPath string, outputFormat string, options ConvertOptions) (*ConvertResult, error) {
+	format, err := LookupOutputFormat(outputFormat, nil)
+	if err != nil {
+		return nil, err
+	}
+	dataset, _, err := Read(path, inputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConvertResult{Dataset: dataset, Format: format, Output: outputPath}
+	if err := remapConverted(result, options); err != nil {
+		return nil, err
+	}
+
+	if result.Output == "" {
+		result.Output = DefaultOutputPath(format, path)
+	}
+	return result, model.WriteDatasetWithImages(format, dataset, result.Output, options.Images)
+}
+
+func remapConverted(result *ConvertResult, options ConvertOptions) error {
+	if options.Remap == nil {
+		return nil
+	}
+
+	report, err := model.RemapDataset(result.Dataset, options.Remap)
+	if err != nil {
+		return err
+	}
+	result.Remap = &report
+	return nil
+}
+
+// Summarize counts the images, annotations and categories of the dataset
+func Summarize(dataset *Dataset) Summary {
+	return model.SummarizeDataset(dataset)
+}
+
+// SummarizeFile reads and summarizes the dataset at the path in the named
+// format, detected from the path when the name is empty. A file of a format
+// with a record decoder, like COCO, is summarized record by record without
+// keeping its annotations.
+func SummarizeFile(path string, format string) (Summary, *Format, error) {
+	inputFormat, err := lookupOrDetect(format, func() (*Format, error) {
+		return model.DetectFormat(path)
+	})
+	if err != nil {
+		return Summary{}, nil, err
+	}
+
+	summarizer := model.NewDatasetSummarizer()
+	if ok, err := decodeRecordsFile(inputFormat, path, summarizer.Records()); ok {
+		if err != nil {
+			return Summary{}, nil, err
+		}
+		return summarizer.Summary(), inputFormat, nil
+	}
+
+	dataset, _, err := Read(path, inputFormat.Name)
+	if err != nil {
+		return Summary{}, nil, err
+	}
+	return Summarize(dataset), inputFormat, nil
+}
+
+// Validate checks the dataset and reports its issues
+func Validate(dataset *Dataset, options ValidationOptions) ValidationReport {
+	return model.ValidateDataset(dataset, options)
+}
+
+// ValidateFile reads and validates the dataset at the path in the named
+// format, detected from the path when the name is empty. A file of a format
+// with a record decoder, like COCO, is validated record by record and read
+// twice, first for the categories and images which the annotations refer
+// to, so only the images, categories and annotation IDs are kept in memory.
+// The image file names are relative to the directory of the file.
+func ValidateFile(path string, format string, options ValidationOptions) (ValidationReport, *Format, error) {
+	inputFormat, err := lookupOrDetect(format, func() (*Format, error) {
+		return model.DetectFormat(path)
+	})
+	if err != nil {
+		return ValidationReport{}, nil, err
+	}
+
+	validator := model.NewDatasetValidator(&Dataset{ImageDir: filepath.Dir(path)}, options)
+	records := validator.Records()
+	imageRecords := model.DatasetRecords{Category: records.Category, Image: records.Image}
+	if ok, err := decodeRecordsFile(inputFormat, path, imageRecords); ok {
+		if err != nil {
+			return ValidationReport{}, nil, err
+		}
+		annotationRecords := model.DatasetRecords{Annotation: records.Annotation}
+		if _, err := decodeRecordsFile(inputFormat, path, annotationRecords); err != nil {
+			return ValidationReport{}, nil, err
+		}
+		return validator.Report(), inputFormat, nil
+	}
+
+	dataset, _, err := Read(path, inputFormat.Name)
+	if err != nil {
+		return ValidationReport{}, nil, err
+	}
+	return Validate(dataset, options), inputFormat, nil
+}
+
+// ParseFailSeverity returns the lowest severity failing a validation, error
+// or warning
+func ParseFailSeverity(name string) (Severity, error) {
+	switch severity := Severity(name); severity {
+	case model.SeverityError, model.SeverityWarning:
+		return severity, nil
+	default:
+		return "", fmt.Errorf("unknown severity [%v], error or warning", name)
+	}
+}
+
+// ValidationPassed reports whether the report has no issue of the severity
+// failOn or above
+func ValidationPassed(report *ValidationReport, failOn Severity) bool {
+	if failOn == model.SeverityWarning {
+		return report.Errors == 0 && report.Warnings == 0
+	}
+	return report.Errors == 0
+}
+
+// Fix repairs the dataset in place and returns the changes
+func Fix(dataset *Dataset, options FixOptions) FixChangelog {
+	return model.FixDataset(dataset, options)
+}
+
+// Analyse computes the box and image statistics of the dataset
+func Analyse(dataset *Dataset, options AnalysisOptions) Analysis {
+	return model.AnalyseDataset(dataset, options)
+}
+
+// RenderCharts renders the charts of the analysis by their png file names,
+// e.g. width.png
+func RenderCharts(analysis *Analysis, dataset *Dataset) map[string]*image.RGBA {
+	return model.RenderAnalysisCharts(analysis, CategoryNames(dataset))
+}
+
+// CategoryNames returns the names of the categories of the dataset in order
+func CategoryNames(dataset *Dataset) []string {
+	categories := make([]string, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		categories[index] = category.Name
+	}
+	return categories
+}
+
+// EstimateAnchors clusters the boxes of the dataset into anchors
+func EstimateAnchors(dataset *Dataset, options AnchorOptions) (AnchorResult, error) {
+	return model.EstimateAnchors(dataset, options)
+}
+
+// Split partitions the images of the dataset into the splits of the options
+func Split(dataset *Dataset, options SplitOptions) ([]DatasetSplit, error) {
+	return model.SplitDataset(dataset, options)
+}
+
+// WriteSplits writes the splits in the format under the directory
+func WriteSplits(format *Format, splits []DatasetSplit, path string) error {
+	return model.WriteDatasetSplits(format, splits, path)
+}
+
+// Folds partitions the images of the dataset into the train/val pairs of
+// the k-fold cross-validation
+func Folds(dataset *Dataset, folds int, options SplitOptions) ([]DatasetFold, error) {
+	return model.FoldDataset(dataset, folds, options)
+}
+
+// WriteFolds writes the train and val splits of every fold in the format
+// under fold-<index> of the directory, and the manifest of the folds made
+// from the source as folds.json
+func WriteFolds(format *Format, folds []DatasetFold, path string, source string, options SplitOptions) error {
+	for _, fold := range folds {
+		splits := []DatasetSplit{
+			{Name: "train", Dataset: fold.Train},
+			{Name: "val", Dataset: fold.Val},
+		}
+		foldPath := filepath.Join(path, fmt.Sprintf("fold-%d", fold.Index))
+		if err := model.WriteDatasetSplits(format, splits, foldPath); err != nil {
+			return err
+		}
+	}
+
+	manifest := model.NewFoldManifest(source, folds, options)
+	return model.WriteFoldManifestToFile(&manifest, filepath.Join(path, "folds.json"))
+}
+
+// Merge merges the source datasets into one, WriteWithNamedImages writes it
+// with the image files under the merged names
+func Merge(sources []MergeSource, options MergeOptions) (*Dataset, MergeReport, error) {
+	return model.MergeDatasets(sources, options)
+}
+
+// Remap renames, merges and drops the categories of the dataset in place
+func Remap(dataset *Dataset, remap *CategoryRemap) (RemapReport, error) {
+	return model.RemapDataset(dataset, remap)
+}
+
+// DecodeCategoryRemap decodes the yaml or json category remap from r
+func DecodeCategoryRemap(r io.Reader) (*CategoryRemap, error) {
+	var remap CategoryRemap
+	if err := model.DecodeCategoryRemap(&remap, r); err != nil {
+		return nil, err
+	}
+	return &remap, nil
+}
+
+// ReadCategoryRemap reads the yaml or json category remap file
+func ReadCategoryRemap(path string) (*CategoryRemap, error) {
+	var remap CategoryRemap
+	if err := model.ReadCategoryRemapFromFile(&remap, path); err != nil {
+		return nil, err
+	}
+	return &remap, nil
+}
+
+// DecodeCategoryMapping decodes the yaml or json mapping of the source
+// category names to the merged names from r
+func DecodeCategoryMapping(r io.Reader) (map[string]string, error) {
+	var mapping map[string]string
+	if err := model.DecodeCategoryMapping(&mapping, r); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// ReadCategoryMapping reads the yaml or json category mapping file
+func ReadCategoryMapping(path string) (map[string]string, error) {
+	var mapping map[string]string
+	if err := model.ReadCategoryMappingFromFile(&mapping, path); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// WriteFixChangelog writes the changelog as a json file
+func WriteFixChangelog(changelog *FixChangelog, path string) error {
+	return model.WriteFixChangelogToFile(changelog, path)
+}
+
+// WriteCharts renders the charts of the analysis as png files in the directory
+func WriteCharts(analysis *Analysis, dataset *Dataset, path string) error {
+	return model.WriteAnalysisChartsToDir(analysis, CategoryNames(dataset), path)
+}
@@ -2,7 +2,11 @@ module github.com/smslit/datasetgo
 
 go 1.18
 
-require github.com/spf13/cobra v1.4.0
+require (
+	github.com/spf13/cobra v1.4.0
+	golang.org/x/image v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
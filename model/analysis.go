@@ -0,0 +1,252 @@
+package model
+
+import (
+	"math"
+	"sort"
+)
+
+// the area thresholds of the COCO small, medium and large objects
+const (
+	SmallObjectArea  = 32 * 32
+	MediumObjectArea = 96 * 96
+)
+
+type HistogramBin struct {
+	Min   float64 `json:"min" yaml:"min"`
+	Max   float64 `json:"max" yaml:"max"`
+	Count int     `json:"count" yaml:"count"`
+}
+
+// MetricStatistics describes the distribution of a box metric
+type MetricStatistics struct {
+	Min       float64        `json:"min" yaml:"min"`
+	Max       float64        `json:"max" yaml:"max"`
+	Mean      float64        `json:"mean" yaml:"mean"`
+	Median    float64        `json:"median" yaml:"median"`
+	Histogram []HistogramBin `json:"histogram" yaml:"histogram"`
+}
+
+// SizeBuckets counts the objects by the COCO relative size
+type SizeBuckets struct {
+	Small  int `json:"small" yaml:"small"`
+	Medium int `json:"medium" yaml:"medium"`
+	Large  int `json:"large" yaml:"large"`
+}
+
+// BoxStatistics is the statistics of the boxes of a category, or of all the
+// boxes when the category is empty
+type BoxStatistics struct {
+	CategoryID  int              `json:"category_id,omitempty" yaml:"category_id,omitempty"`
+	Category    string           `json:"category,omitempty" yaml:"category,omitempty"`
+	Boxes       int              `json:"boxes" yaml:"boxes"`
+	Width       MetricStatistics `json:"width" yaml:"width"`
+	Height      MetricStatistics `json:"height" yaml:"height"`
+	AspectRatio MetricStatistics `json:"aspect_ratio" yaml:"aspect_ratio"`
+	Area        MetricStatistics `json:"area" yaml:"area"`
+	Sizes       SizeBuckets      `json:"sizes" yaml:"sizes"`
+}
+
+type ObjectsPerImage struct {
+	Objects int `json:"objects" yaml:"objects"`
+	Images  int `json:"images" yaml:"images"`
+}
+
+// DatasetAnalysis is the box and class statistics of a dataset
+type DatasetAnalysis struct {
+	// the statistics of all the boxes and then of each category
+	All        BoxStatistics   `json:"all" yaml:"all"`
+	Categories []BoxStatistics `json:"categories" yaml:"categories"`
+
+	ObjectsPerImage []ObjectsPerImage `json:"objects_per_image" yaml:"objects_per_image"`
+
+	// the number of box centers in each cell of the image divided into a
+	// grid, the rows go from top to bottom
+	CenterHeatmap [][]int `json:"center_heatmap" yaml:"center_heatmap"`
+
+	// the number of images where both categories appear, in the order of the
+	// categories, the diagonal is the number of images of the category
+	CoOccurrence [][]int `json:"co_occurrence" yaml:"co_occurrence"`
+}
+
+type AnalysisOptions struct {
+	// the number of bins of the histograms
+	Bins int
+
+	// the number of cells of each side of the center heatmap
+	HeatmapSize int
+}
+
+// AnalyseDataset computes the box and class statistics of the dataset
+func AnalyseDataset(dataset *Dataset, options AnalysisOptions) DatasetAnalysis {
+	if options.Bins <= 0 {
+		options.Bins = 10
+	}
+	if options.HeatmapSize <= 0 {
+		options.HeatmapSize = 10
+	}
+
+	imageMap := dataset.ImageMap()
+	categoryIndexes := make(map[int]int, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		categoryIndexes[category.ID] = index
+	}
+
+	analysis := DatasetAnalysis{
+		Categories:      make([]BoxStatistics, len(dataset.Categories)),
+		ObjectsPerImage: make([]ObjectsPerImage, 0),
+		CenterHeatmap:   make([][]int, options.HeatmapSize),
+		CoOccurrence:    make([][]int, len(dataset.Categories)),
+	}
+	for index := range analysis.CenterHeatmap {
+		analysis.CenterHeatmap[index] = make([]int, options.HeatmapSize)
+	}
+	for index := range analysis.CoOccurrence {
+		analysis.CoOccurrence[index] = make([]int, len(dataset.Categories))
+	}
+
+	allBoxes := make([]BoundingBox, 0, len(dataset.Annotations))
+	categoryBoxes := make([][]BoundingBox, len(dataset.Categories))
+	for _, annotation := range dataset.Annotations {
+		allBoxes = append(allBoxes, annotation.BBox)
+		if index, ok := categoryIndexes[annotation.CategoryID]; ok {
+			categoryBoxes[index] = append(categoryBoxes[index], annotation.BBox)
+		}
+
+		image, ok := imageMap[annotation.ImageID]
+		if !ok || image.Width <= 0 || image.Height <= 0 {
+			continue
+		}
+		centerX := (annotation.BBox.X + annotation.BBox.Width/2) / float64(image.Width)
+		centerY := (annotation.BBox.Y + annotation.BBox.Height/2) / float64(image.Height)
+		column := clampInt(int(centerX*float64(options.HeatmapSize)), 0, options.HeatmapSize-1)
+		row := clampInt(int(centerY*float64(options.HeatmapSize)), 0, options.HeatmapSize-1)
+		analysis.CenterHeatmap[row][column]++
+	}
+
+	analysis.All = boxStatistics(allBoxes, options.Bins)
+	for index, category := range dataset.Categories {
+		analysis.Categories[index] = boxStatistics(categoryBoxes[index], options.Bins)
+		analysis.Categories[index].CategoryID = category.ID
+		analysis.Categories[index].Category = category.Name
+	}
+
+	// the objects and categories of every image
+	annotationMap := dataset.AnnotationsByImage()
+	objectCounts := make(map[int]int)
+	for _, image := range dataset.Images {
+		annotations := annotationMap[image.ID]
+		objectCounts[len(annotations)]++
+
+		present := make([]int, 0)
+		seen := make(map[int]bool)
+		for _, annotation := range annotations {
+			index, ok := categoryIndexes[annotation.CategoryID]
+			if ok && !seen[index] {
+				seen[index] = true
+				present = append(present, index)
+			}
+		}
+		for _, i := range present {
+			for _, j := range present {
+				analysis.CoOccurrence[i][j]++
+			}
+		}
+	}
+	for objects, images := range objectCounts {
+		analysis.ObjectsPerImage = append(analysis.ObjectsPerImage, ObjectsPerImage{
+			Objects: objects,
+			Images:  images,
+		})
+	}
+	sort.Slice(analysis.ObjectsPerImage, func(i, j int) bool {
+		return analysis.ObjectsPerImage[i].Objects < analysis.ObjectsPerImage[j].Objects
+	})
+
+	return analysis
+}
+
+func boxStatistics(boxes []BoundingBox, bins int) BoxStatistics {
+	widths := make([]float64, len(boxes))
+	heights := make([]float64, len(boxes))
+	aspectRatios := make([]float64, 0, len(boxes))
+	areas := make([]float64, len(boxes))
+
+	statistics := BoxStatistics{Boxes: len(boxes)}
+	for index, box := range boxes {
+		widths[index] = box.Width
+		heights[index] = box.Height
+		areas[index] = box.Width * box.Height
+		if box.Height > 0 {
+			aspectRatios = append(aspectRatios, box.Width/box.Height)
+		}
+
+		switch {
+		case areas[index] < SmallObjectArea:
+			statistics.Sizes.Small++
+		case areas[index] < MediumObjectArea:
+			statistics.Sizes.Medium++
+		default:
+			statistics.Sizes.Large++
+		}
+	}
+
+	statistics.Width = metricStatistics(widths, bins)
+	statistics.Height = metricStatistics(heights, bins)
+	statistics.AspectRatio = metricStatistics(aspectRatios, bins)
+	statistics.Area = metricStatistics(areas, bins)
+	return statistics
+}
+
+// metricStatistics summarizes the values with a histogram of equal width bins
+func metricStatistics(values []float64, bins int) MetricStatistics {
+	statistics := MetricStatistics{Histogram: make([]HistogramBin, 0)}
+	if len(values) == 0 {
+		return statistics
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	statistics.Min = sorted[0]
+	statistics.Max = sorted[len(sorted)-1]
+	if middle := len(sorted) / 2; len(sorted)%2 == 1 {
+		statistics.Median = sorted[middle]
+	} else {
+		statistics.Median = (sorted[middle-1] + sorted[middle]) / 2
+	}
+	sum := 0.0
+	for _, value := range sorted {
+		sum += value
+	}
+	statistics.Mean = sum / float64(len(sorted))
+
+	if statistics.Max == statistics.Min {
+		bins = 1
+	}
+	width := (statistics.Max - statistics.Min) / float64(bins)
+	statistics.Histogram = make([]HistogramBin, bins)
+	for index := range statistics.Histogram {
+		statistics.Histogram[index] = HistogramBin{
+			Min: statistics.Min + float64(index)*width,
+			Max: statistics.Min + float64(index+1)*width,
+		}
+	}
+	statistics.Histogram[bins-1].Max = statistics.Max
+	for _, value := range sorted {
+		index := bins - 1
+		if width > 0 {
+			index = clampInt(int(math.Floor((value-statistics.Min)/width)), 0, bins-1)
+		}
+		statistics.Histogram[index].Count++
+	}
+	return statistics
+}
+
+func clampInt(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
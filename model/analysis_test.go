@@ -0,0 +1,133 @@
+package model
+
+import (
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyseDataset(t *testing.T) {
+	var dataset Dataset
+	if err := ReadDatasetFromCOCOFile(&dataset, filepath.Join("testdata", "coco.json")); err != nil {
+		t.Fatal(err)
+	}
+	analysis := AnalyseDataset(&dataset, AnalysisOptions{Bins: 2, HeatmapSize: 10})
+
+	// the boxes are 38.5x43 and 99x77 in a.jpg of 100x80, 23x13 in b.jpg
+	all := analysis.All
+	if all.Boxes != 3 || all.Sizes != (SizeBuckets{Small: 1, Medium: 2}) {
+		t.Errorf("all the boxes = %v in %+v, want 3 in 1 small and 2 medium", all.Boxes, all.Sizes)
+	}
+	width := MetricStatistics{
+		Min:       23,
+		Max:       99,
+		Mean:      53.5,
+		Median:    38.5,
+		Histogram: []HistogramBin{{Min: 23, Max: 61, Count: 2}, {Min: 61, Max: 99, Count: 1}},
+	}
+	if !reflect.DeepEqual(all.Width, width) {
+		t.Errorf("width = %+v, want %+v", all.Width, width)
+	}
+	if all.Height.Min != 13 || all.Height.Max != 77 || all.Height.Median != 43 {
+		t.Errorf("height = %+v, want from 13 to 77 with the median 43", all.Height)
+	}
+	if math.Abs(all.Area.Mean-(1655.5+7623+299)/3) > 1e-9 {
+		t.Errorf("mean area = %v, want %v", all.Area.Mean, (1655.5+7623+299)/3)
+	}
+	if math.Abs(all.AspectRatio.Max-23.0/13) > 1e-9 {
+		t.Errorf("max aspect ratio = %v, want %v", all.AspectRatio.Max, 23.0/13)
+	}
+
+	if len(analysis.Categories) != 2 {
+		t.Fatalf("got the statistics of %v categories, want 2", len(analysis.Categories))
+	}
+	car, dog := analysis.Categories[0], analysis.Categories[1]
+	if car.CategoryID != 1 || car.Category != "car" || car.Boxes != 1 || car.Width.Histogram[0].Count != 1 {
+		t.Errorf("car = %+v, want a box", car)
+	}
+	if dog.CategoryID != 2 || dog.Category != "dog" || dog.Boxes != 2 || dog.Width.Median != 61 || dog.Sizes != (SizeBuckets{Small: 1, Medium: 1}) {
+		t.Errorf("dog = %+v, want 2 boxes of the median width 61", dog)
+	}
+
+	objects := []ObjectsPerImage{{Objects: 1, Images: 1}, {Objects: 2, Images: 1}}
+	if !reflect.DeepEqual(analysis.ObjectsPerImage, objects) {
+		t.Errorf("objects per image = %+v, want %+v", analysis.ObjectsPerImage, objects)
+	}
+	heatmap := make([][]int, 10)
+	for index := range heatmap {
+		heatmap[index] = make([]int, 10)
+	}
+	heatmap[5][3], heatmap[5][4], heatmap[1][1] = 1, 1, 1
+	if !reflect.DeepEqual(analysis.CenterHeatmap, heatmap) {
+		t.Errorf("center heatmap = %v, want %v", analysis.CenterHeatmap, heatmap)
+	}
+	if coOccurrence := [][]int{{1, 1}, {1, 2}}; !reflect.DeepEqual(analysis.CoOccurrence, coOccurrence) {
+		t.Errorf("co-occurrence = %v, want %v", analysis.CoOccurrence, coOccurrence)
+	}
+}
+
+func TestAnalyseEmptyDataset(t *testing.T) {
+	analysis := AnalyseDataset(&Dataset{}, AnalysisOptions{})
+	if analysis.All.Boxes != 0 || len(analysis.All.Width.Histogram) != 0 || len(analysis.CenterHeatmap) != 10 {
+		t.Errorf("analysis = %+v, want no boxes and the default heatmap", analysis)
+	}
+}
+
+func TestWriteAnalysisCharts(t *testing.T) {
+	var dataset Dataset
+	if err := ReadDatasetFromCOCOFile(&dataset, filepath.Join("testdata", "coco.json")); err != nil {
+		t.Fatal(err)
+	}
+	analysis := AnalyseDataset(&dataset, AnalysisOptions{})
+	dir := t.TempDir()
+	if err := WriteAnalysisChartsToDir(&analysis, []string{"car", "dog"}, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"width.png", "height.png", "aspect_ratio.png", "area.png", "center_heatmap.png", "co_occurrence.png", "objects_per_image.png", "sizes.png"}
+	for _, name := range names {
+		if _, err := readTestPNG(filepath.Join(dir, name)); err != nil {
+			t.Errorf("chart %v: %v", name, err)
+		}
+	}
+
+	// the pixels are compared as the png encoding may change
+	path := filepath.Join(dir, "co_occurrence.png")
+	golden := filepath.Join("testdata", "charts", "co_occurrence.png")
+	if *update {
+		checkGolden(t, path, golden)
+	}
+	got, err := readTestPNG(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := readTestPNG(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rect != want.Rect || !reflect.DeepEqual(got.Pix, want.Pix) {
+		t.Errorf("the chart %v differs from %v", path, golden)
+	}
+}
+
+// readTestPNG decodes the png file into RGBA pixels
+func readTestPNG(path string) (*image.RGBA, error) {
+	pngFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer pngFile.Close()
+
+	decoded, err := png.Decode(pngFile)
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(decoded.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+	return rgba, nil
+}
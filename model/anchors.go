@@ -0,0 +1,295 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+type AnchorOptions struct {
+	// the number of anchors
+	Clusters int
+
+	// the training image size, the boxes are scaled as their images are
+	// resized to it on the longest side, no scaling if not positive
+	ImageSize int
+
+	// the generations of the genetic refinement after k-means, none if 0
+	Generations int
+
+	// the largest ratio between the sides of a box and an anchor for the
+	// anchor to fit the box, 4 as YOLOv5 does
+	Threshold float64
+
+	Seed int64
+}
+
+type Anchor struct {
+	Width  float64 `json:"width" yaml:"width"`
+	Height float64 `json:"height" yaml:"height"`
+}
+
+// AnchorResult is the anchors fitted to the boxes, sorted by area
+type AnchorResult struct {
+	Anchors []Anchor `json:"anchors" yaml:"anchors"`
+	Boxes   int      `json:"boxes" yaml:"boxes"`
+
+	// the mean over the boxes of the best IoU with any anchor
+	MeanBestIoU float64 `json:"mean_best_iou" yaml:"mean_best_iou"`
+
+	// the share of the boxes which some anchor fits within the threshold
+	BestPossibleRecall float64 `json:"best_possible_recall" yaml:"best_possible_recall"`
+
+	// the mean number of anchors fitting a box within the threshold
+	AnchorsPerBox float64 `json:"anchors_per_box" yaml:"anchors_per_box"`
+}
+
+// EstimateAnchors runs k-means with the 1-IoU distance over the sizes of the
+// boxes, optionally refined by a genetic algorithm maximizing the mean best IoU
+func EstimateAnchors(dataset *Dataset, options AnchorOptions) (AnchorResult, error) {
+	if options.Clusters <= 0 {
+		return AnchorResult{}, errors.New("the number of anchors must be positive")
+	}
+	if options.Threshold <= 1 {
+		options.Threshold = 4
+	}
+
+	boxes := anchorBoxes(dataset, options.ImageSize)
+	if len(boxes) < options.Clusters {
+		return AnchorResult{}, fmt.Errorf("got %v boxes for %v anchors", len(boxes), options.Clusters)
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+	anchors := kmeansAnchors(boxes, options.Clusters, random)
+	if options.Generations > 0 {
+		anchors = evolveAnchors(boxes, anchors, options.Generations, random)
+	}
+
+	sort.Slice(anchors, func(i, j int) bool {
+		return anchors[i].Width*anchors[i].Height < anchors[j].Width*anchors[j].Height
+	})
+	return anchorMetrics(boxes, anchors, options.Threshold), nil
+}
+
+// anchorBoxes returns the box sizes scaled to the training image size, the
+// boxes smaller than 2 pixels are left out
+func anchorBoxes(dataset *Dataset, imageSize int) []Anchor {
+	imageMap := dataset.ImageMap()
+	boxes := make([]Anchor, 0, len(dataset.Annotations))
+	for _, annotation := range dataset.Annotations {
+		scale := 1.0
+		if imageSize > 0 {
+			image, ok := imageMap[annotation.ImageID]
+			if !ok || image.Width <= 0 || image.Height <= 0 {
+				continue
+			}
+			scale = float64(imageSize) / float64(maxInt(image.Width, image.Height))
+		}
+
+		box := Anchor{
+			Width:  annotation.BBox.Width * scale,
+			Height: annotation.BBox.Height * scale,
+		}
+		if box.Width >= 2 && box.Height >= 2 {
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes
+}
+
+// anchorIoU is the IoU of two boxes sharing their center
+func anchorIoU(a Anchor, b Anchor) float64 {
+	intersection := math.Min(a.Width, b.Width) * math.Min(a.Height, b.Height)
+	return intersection / (a.Width*a.Height + b.Width*b.Height - intersection)
+}
+
+func bestIoU(box Anchor, anchors []Anchor) (float64, int) {
+	best, bestIndex := -1.0, 0
+	for index, anchor := range anchors {
+		if iou := anchorIoU(box, anchor); iou > best {
+			best, bestIndex = iou, index
+		}
+	}
+	return best, bestIndex
+}
+
+func meanBestIoU(boxes []Anchor, anchors []Anchor) float64 {
+	sum := 0.0
+	for _, box := range boxes {
+		iou, _ := bestIoU(box, anchors)
+		sum += iou
+	}
+	return sum / float64(len(boxes))
+}
+
+// kmeansAnchors clusters the boxes with the 1-IoU distance, the clusters are
+// seeded by k-means++ and their centers are the medians of their boxes
+func kmeansAnchors(boxes []Anchor, clusters int, random *rand.Rand) []Anchor {
+	anchors := make([]Anchor, 0, clusters)
+	anchors = append(anchors, boxes[random.Intn(len(boxes))])
+	distances := make([]float64, len(boxes))
+	for len(anchors) < clusters {
+		sum := 0.0
+		for index, box := range boxes {
+			iou, _ := bestIoU(box, anchors)
+			distances[index] = (1 - iou) * (1 - iou)
+			sum += distances[index]
+		}
+		target := random.Float64() * sum
+		chosen := len(boxes) - 1
+		for index, distance := range distances {
+			if target -= distance; target <= 0 {
+				chosen = index
+				break
+			}
+		}
+		anchors = append(anchors, boxes[chosen])
+	}
+
+	assignments := make([]int, len(boxes))
+	for index := range assignments {
+		assignments[index] = -1
+	}
+	for iteration := 0; iteration < 300; iteration++ {
+		changed := false
+		for index, box := range boxes {
+			if _, nearest := bestIoU(box, anchors); nearest != assignments[index] {
+				assignments[index] = nearest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		widths := make([][]float64, clusters)
+		heights := make([][]float64, clusters)
+		for index, box := range boxes {
+			widths[assignments[index]] = append(widths[assignments[index]], box.Width)
+			heights[assignments[index]] = append(heights[assignments[index]], box.Height)
+		}
+		for cluster := range anchors {
+			// an empty cluster keeps its center
+			if len(widths[cluster]) > 0 {
+				anchors[cluster] = Anchor{
+					Width:  median(widths[cluster]),
+					Height: median(heights[cluster]),
+				}
+			}
+		}
+	}
+	return anchors
+}
+
+// evolveAnchors mutates the anchors for the generations, keeping every
+// mutation which raises the mean best IoU
+func evolveAnchors(boxes []Anchor, anchors []Anchor, generations int, random *rand.Rand) []Anchor {
+	// mutate few sides by little, the k-means anchors are close to the optimum
+	const mutationProbability, sigma = 0.2, 0.05
+
+	best := append([]Anchor(nil), anchors...)
+	bestFitness := meanBestIoU(boxes, best)
+	candidate := make([]Anchor, len(best))
+	for generation := 0; generation < generations; generation++ {
+		mutated := false
+		for !mutated {
+			for index, anchor := range best {
+				candidate[index] = anchor
+				if random.Float64() < mutationProbability {
+					factor := math.Min(math.Max(random.NormFloat64()*sigma+1, 0.3), 3)
+					candidate[index].Width = math.Max(anchor.Width*factor, 2)
+					mutated = mutated || factor != 1
+				}
+				if random.Float64() < mutationProbability {
+					factor := math.Min(math.Max(random.NormFloat64()*sigma+1, 0.3), 3)
+					candidate[index].Height = math.Max(anchor.Height*factor, 2)
+					mutated = mutated || factor != 1
+				}
+			}
+		}
+
+		if fitness := meanBestIoU(boxes, candidate); fitness > bestFitness {
+			bestFitness = fitness
+			copy(best, candidate)
+		}
+	}
+	return best
+}
+
+// anchorMetrics measures how the anchors fit the boxes, an anchor fits a box
+// when neither side differs by more than the threshold times
+func anchorMetrics(boxes []Anchor, anchors []Anchor, threshold float64) AnchorResult {
+	result := AnchorResult{
+		Anchors:     anchors,
+		Boxes:       len(boxes),
+		MeanBestIoU: meanBestIoU(boxes, anchors),
+	}
+
+	recalled, fits := 0, 0
+	for _, box := range boxes {
+		bestRatio := 0.0
+		for _, anchor := range anchors {
+			ratio := math.Min(
+				math.Min(box.Width/anchor.Width, anchor.Width/box.Width),
+				math.Min(box.Height/anchor.Height, anchor.Height/box.Height),
+			)
+			if ratio > 1/threshold {
+				fits++
+			}
+			bestRatio = math.Max(bestRatio, ratio)
+		}
+		if bestRatio > 1/threshold {
+			recalled++
+		}
+	}
+	result.BestPossibleRecall = float64(recalled) / float64(len(boxes))
+	result.AnchorsPerBox = float64(fits) / float64(len(boxes))
+	return result
+}
+
+// DarknetCfg returns the anchors line of a darknet yolo cfg
+func (result *AnchorResult) DarknetCfg() string {
+	values := make([]string, len(result.Anchors))
+	for index, anchor := range result.Anchors {
+		values[index] = fmt.Sprintf("%d,%d", int(math.Round(anchor.Width)), int(math.Round(anchor.Height)))
+	}
+	return fmt.Sprintf("anchors = %v\nnum = %v\n", strings.Join(values, ", "), len(values))
+}
+
+// UltralyticsYAML returns the anchors of an ultralytics model yaml, three
+// output layers from P3/8 when the anchors divide evenly
+func (result *AnchorResult) UltralyticsYAML() string {
+	layers := 1
+	if len(result.Anchors)%3 == 0 {
+		layers = 3
+	}
+	perLayer := len(result.Anchors) / layers
+
+	var builder strings.Builder
+	builder.WriteString("anchors:\n")
+	for layer := 0; layer < layers; layer++ {
+		values := make([]string, perLayer)
+		for index, anchor := range result.Anchors[layer*perLayer : (layer+1)*perLayer] {
+			values[index] = fmt.Sprintf("%d,%d", int(math.Round(anchor.Width)), int(math.Round(anchor.Height)))
+		}
+		builder.WriteString(fmt.Sprintf("  - [%v]", strings.Join(values, ", ")))
+		if layers == 3 {
+			builder.WriteString(fmt.Sprintf("  # P%d/%d", layer+3, 8<<layer))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newAnchorTestDataset returns boxes of three sizes on 640x640 images
+func newAnchorTestDataset() *Dataset {
+	dataset := &Dataset{Categories: []DatasetCategory{{ID: 1, Name: "car"}}}
+	imageID := dataset.AddImage(DatasetImage{FileName: "a.jpg", Width: 640, Height: 640})
+	for index := 0; index < 30; index++ {
+		offset := float64(index % 5)
+		for _, size := range [][2]float64{{10, 20}, {60, 40}, {200, 300}} {
+			dataset.AddAnnotation(DatasetAnnotation{
+				ImageID:    imageID,
+				CategoryID: 1,
+				BBox:       BoundingBox{Width: size[0] + offset, Height: size[1] + offset},
+			})
+		}
+	}
+	return dataset
+}
+
+func TestEstimateAnchors(t *testing.T) {
+	dataset := newAnchorTestDataset()
+	options := AnchorOptions{Clusters: 3, Generations: 100, Seed: 7}
+	result, err := EstimateAnchors(dataset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Boxes != 90 || len(result.Anchors) != 3 {
+		t.Fatalf("got %v anchors of %v boxes, want 3 of 90", len(result.Anchors), result.Boxes)
+	}
+	for index := 1; index < len(result.Anchors); index++ {
+		previous, anchor := result.Anchors[index-1], result.Anchors[index]
+		if previous.Width*previous.Height > anchor.Width*anchor.Height {
+			t.Errorf("the anchors %+v are not sorted by area", result.Anchors)
+		}
+	}
+	if result.MeanBestIoU < 0.9 || result.BestPossibleRecall != 1 {
+		t.Errorf("mean best IoU = %v and best possible recall = %v, want the clusters fitted", result.MeanBestIoU, result.BestPossibleRecall)
+	}
+
+	again, err := EstimateAnchors(dataset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, result) {
+		t.Errorf("the anchors %+v differ from %+v for the same seed", again.Anchors, result.Anchors)
+	}
+}
+
+func TestEstimateAnchorsErrors(t *testing.T) {
+	dataset := newAnchorTestDataset()
+	if _, err := EstimateAnchors(dataset, AnchorOptions{Clusters: 0}); err == nil {
+		t.Error("estimated no anchors")
+	}
+
+	// the boxes under 2 pixels are left out
+	dataset.Annotations = []DatasetAnnotation{
+		{ID: 1, ImageID: 1, CategoryID: 1, BBox: BoundingBox{Width: 10, Height: 10}},
+		{ID: 2, ImageID: 1, CategoryID: 1, BBox: BoundingBox{Width: 1, Height: 10}},
+	}
+	_, err := EstimateAnchors(dataset, AnchorOptions{Clusters: 2})
+	if err == nil || err.Error() != "got 1 boxes for 2 anchors" {
+		t.Errorf("error = %v, want too few boxes", err)
+	}
+}
+
+func TestAnchorFormats(t *testing.T) {
+	anchors := []Anchor{{10, 13}, {16, 30}, {33, 23}, {30, 61}, {62, 45}, {59.4, 119.5}}
+	tests := []struct {
+		anchors     []Anchor
+		darknet     string
+		ultralytics string
+	}{
+		{
+			anchors: anchors,
+			darknet: "anchors = 10,13, 16,30, 33,23, 30,61, 62,45, 59,120\nnum = 6\n",
+			ultralytics: "anchors:\n" +
+				"  - [10,13, 16,30]  # P3/8\n" +
+				"  - [33,23, 30,61]  # P4/16\n" +
+				"  - [62,45, 59,120]  # P5/32\n",
+		},
+		{
+			// the anchors which do not divide by 3 are a single layer
+			anchors:     anchors[:4],
+			darknet:     "anchors = 10,13, 16,30, 33,23, 30,61\nnum = 4\n",
+			ultralytics: "anchors:\n  - [10,13, 16,30, 33,23, 30,61]\n",
+		},
+	}
+	for _, test := range tests {
+		result := AnchorResult{Anchors: test.anchors}
+		if got := result.DarknetCfg(); got != test.darknet {
+			t.Errorf("darknet cfg = %q, want %q", got, test.darknet)
+		}
+		if got := result.UltralyticsYAML(); got != test.ultralytics {
+			t.Errorf("ultralytics yaml = %q, want %q", got, test.ultralytics)
+		}
+	}
+}
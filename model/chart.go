@@ -0,0 +1,240 @@
+package model
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// the size of the characters of the chart font
+const (
+	chartCharWidth  = 7
+	chartCharHeight = 13
+)
+
+var (
+	chartBackground = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	chartForeground = color.RGBA{R: 33, G: 33, B: 33, A: 255}
+	chartAxis       = color.RGBA{R: 158, G: 158, B: 158, A: 255}
+	chartBar        = color.RGBA{R: 66, G: 133, B: 244, A: 255}
+)
+
+// RenderBarChart draws the values as vertical bars with the labels below
+func RenderBarChart(title string, labels []string, values []int) *image.RGBA {
+	const width, height = 720, 420
+	const left, right, top, bottom = 60, 20, 40, 60
+
+	chart := newChart(width, height)
+	drawChartText(chart, (width-len(title)*chartCharWidth)/2, 24, title, chartForeground)
+
+	maxValue := 1
+	for _, value := range values {
+		maxValue = maxInt(maxValue, value)
+	}
+
+	plotWidth, plotHeight := width-left-right, height-top-bottom
+	fillRect(chart, image.Rect(left, height-bottom, width-right, height-bottom+1), chartAxis)
+	fillRect(chart, image.Rect(left-1, top, left, height-bottom), chartAxis)
+	drawChartText(chart, left-8-len(strconv.Itoa(maxValue))*chartCharWidth, top+chartCharHeight/2, strconv.Itoa(maxValue), chartForeground)
+	drawChartText(chart, left-8-chartCharWidth, height-bottom, "0", chartForeground)
+
+	if len(values) == 0 {
+		return chart
+	}
+
+	slot := plotWidth / len(values)
+	// label every n-th bar so that the labels do not overlap
+	labelStep := 1
+	for _, label := range labels {
+		for (len(label)+1)*chartCharWidth > slot*labelStep {
+			labelStep++
+		}
+	}
+
+	for index, value := range values {
+		barHeight := value * plotHeight / maxValue
+		x0 := left + index*slot + slot/8
+		x1 := left + (index+1)*slot - slot/8
+		if x1 <= x0 {
+			x1 = x0 + 1
+		}
+		fillRect(chart, image.Rect(x0, height-bottom-barHeight, x1, height-bottom), chartBar)
+
+		if index%labelStep == 0 && index < len(labels) {
+			label := labels[index]
+			drawChartText(chart, left+index*slot+(slot-len(label)*chartCharWidth)/2, height-bottom+chartCharHeight+4, label, chartForeground)
+		}
+		if count := strconv.Itoa(value); len(count)*chartCharWidth <= slot {
+			drawChartText(chart, left+index*slot+(slot-len(count)*chartCharWidth)/2, height-bottom-barHeight-4, count, chartForeground)
+		}
+	}
+	return chart
+}
+
+// RenderHistogramChart draws the histogram as a bar chart labeled by the
+// lower bound of every bin
+func RenderHistogramChart(title string, histogram []HistogramBin) *image.RGBA {
+	labels := make([]string, len(histogram))
+	values := make([]int, len(histogram))
+	for index, bin := range histogram {
+		labels[index] = strconv.FormatFloat(bin.Min, 'g', 4, 64)
+		values[index] = bin.Count
+	}
+	return RenderBarChart(title, labels, values)
+}
+
+// RenderHeatmapChart draws the grid as colored cells, the darker the larger
+// the value, with the optional labels of the rows and columns
+func RenderHeatmapChart(title string, grid [][]int, labels []string) *image.RGBA {
+	labelWidth := 0
+	for _, label := range labels {
+		labelWidth = maxInt(labelWidth, len(label)*chartCharWidth+8)
+	}
+
+	rows := len(grid)
+	columns := 0
+	maxValue := 1
+	for _, row := range grid {
+		columns = maxInt(columns, len(row))
+		for _, value := range row {
+			maxValue = maxInt(maxValue, value)
+		}
+	}
+
+	cell := 40
+	if rows > 0 && columns > 0 {
+		cell = clampInt(640/maxInt(rows, columns), 8, 60)
+	}
+	left, top := 20+labelWidth, 40
+	if len(labels) > 0 {
+		top += chartCharHeight + 4
+	}
+	width := maxInt(left+columns*cell+20, len(title)*chartCharWidth+40)
+	height := top + rows*cell + 20
+
+	chart := newChart(width, height)
+	drawChartText(chart, (width-len(title)*chartCharWidth)/2, 24, title, chartForeground)
+
+	for row, values := range grid {
+		for column, value := range values {
+			// from white to the bar color
+			ratio := float64(value) / float64(maxValue)
+			cellColor := color.RGBA{
+				R: uint8(255 - ratio*float64(255-chartBar.R)),
+				G: uint8(255 - ratio*float64(255-chartBar.G)),
+				B: uint8(255 - ratio*float64(255-chartBar.B)),
+				A: 255,
+			}
+			x, y := left+column*cell, top+row*cell
+			fillRect(chart, image.Rect(x, y, x+cell-1, y+cell-1), cellColor)
+
+			text := strconv.Itoa(value)
+			if len(text)*chartCharWidth <= cell-2 && chartCharHeight <= cell-2 {
+				textColor := chartForeground
+				if ratio > 0.5 {
+					textColor = chartBackground
+				}
+				drawChartText(chart, x+(cell-len(text)*chartCharWidth)/2, y+(cell+chartCharHeight)/2-2, text, textColor)
+			}
+		}
+	}
+
+	for index, label := range labels {
+		drawChartText(chart, 12, top+index*cell+(cell+chartCharHeight)/2-2, label, chartForeground)
+		short := label
+		if maxLength := cell / chartCharWidth; len(short) > maxLength {
+			short = short[:maxLength]
+		}
+		drawChartText(chart, left+index*cell+(cell-len(short)*chartCharWidth)/2, top-6, short, chartForeground)
+	}
+	return chart
+}
+
+// WriteAnalysisChartsToDir renders the charts of the analysis as png files
+// in the directory
+func WriteAnalysisChartsToDir(analysis *DatasetAnalysis, categories []string, path string) error {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+
+	for name, chart := range RenderAnalysisCharts(analysis, categories) {
+		if err := writePNG(chart, filepath.Join(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderAnalysisCharts renders the charts of the analysis by their png file
+// names, e.g. width.png
+func RenderAnalysisCharts(analysis *DatasetAnalysis, categories []string) map[string]*image.RGBA {
+	charts := map[string]*image.RGBA{
+		"width.png":          RenderHistogramChart("box width (px)", analysis.All.Width.Histogram),
+		"height.png":         RenderHistogramChart("box height (px)", analysis.All.Height.Histogram),
+		"aspect_ratio.png":   RenderHistogramChart("box aspect ratio (width/height)", analysis.All.AspectRatio.Histogram),
+		"area.png":           RenderHistogramChart("box area (px^2)", analysis.All.Area.Histogram),
+		"center_heatmap.png": RenderHeatmapChart("box centers", analysis.CenterHeatmap, nil),
+		"co_occurrence.png":  RenderHeatmapChart("class co-occurrence (images)", analysis.CoOccurrence, categories),
+	}
+
+	labels := make([]string, len(analysis.ObjectsPerImage))
+	values := make([]int, len(analysis.ObjectsPerImage))
+	for index, objects := range analysis.ObjectsPerImage {
+		labels[index] = strconv.Itoa(objects.Objects)
+		values[index] = objects.Images
+	}
+	charts["objects_per_image.png"] = RenderBarChart("objects per image", labels, values)
+
+	sizeLabels := make([]string, 0, len(analysis.Categories)*3)
+	sizeValues := make([]int, 0, len(analysis.Categories)*3)
+	for _, statistics := range analysis.Categories {
+		sizeLabels = append(sizeLabels, statistics.Category+"/S", statistics.Category+"/M", statistics.Category+"/L")
+		sizeValues = append(sizeValues, statistics.Sizes.Small, statistics.Sizes.Medium, statistics.Sizes.Large)
+	}
+	charts["sizes.png"] = RenderBarChart("objects by size (small/medium/large)", sizeLabels, sizeValues)
+
+	return charts
+}
+
+func newChart(width int, height int) *image.RGBA {
+	chart := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(chart, chart.Bounds(), image.NewUniform(chartBackground), image.Point{}, draw.Src)
+	return chart
+}
+
+func fillRect(chart *image.RGBA, rect image.Rectangle, fill color.Color) {
+	draw.Draw(chart, rect, image.NewUniform(fill), image.Point{}, draw.Src)
+}
+
+// drawChartText draws the text with its baseline at y
+func drawChartText(chart *image.RGBA, x int, y int, text string, textColor color.Color) {
+	drawer := font.Drawer{
+		Dst:  chart,
+		Src:  image.NewUniform(textColor),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+func writePNG(img image.Image, path string) error {
+	pngFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(pngFile, img); err != nil {
+		pngFile.Close()
+		return fmt.Errorf("%v writing... %v", path, err.Error())
+	}
+	return pngFile.Close()
+}
@@ -1,19 +1,39 @@
 package model
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
-	"image"
-	_ "image/jpeg"
+	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 )
 
+func init() {
+	MustRegisterFormat(&Format{
+		Name:        "coco",
+		Description: "COCO",
+		Extension:   ".json",
+		Detect:      detectFromOS(detectCOCOFile),
+		Reader:      DatasetReaderFunc(ReadDatasetFromCOCOFile),
+		Writer:      cocoWriter{},
+		DetectFS:    detectCOCOFile,
+		FSReader:    DatasetFSReaderFunc(ReadDatasetFromCOCOFS),
+		Decoder:     DatasetDecoderFunc(DecodeCOCOStream),
+		Encoder:     DatasetEncoderFunc(EncodeCOCOStream),
+
+		RecordDecoder: DatasetRecordDecoderFunc(DecodeCOCORecords),
+	})
+}
+
 type COCOInfo struct {
-	Year        string `json:"year"`
+	Year        int    `json:"year"`
 	Version     string `json:"version"`
 	Description string `json:"description"`
 	Contributor string `json:"contributor"`
@@ -21,6 +41,56 @@ type COCOInfo struct {
 	DateCreated string `json:"date_created"`
 }
 
+// UnmarshalJSON decodes the info, the year is a number like the 2017 of the
+// coco datasets but a string such as "2022" is accepted as well
+func (info *COCOInfo) UnmarshalJSON(data []byte) error {
+	type plainInfo COCOInfo
+	var fields struct {
+		plainInfo
+		Year json.RawMessage `json:"year"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	*info = COCOInfo(fields.plainInfo)
+	year, err := decodeCOCOYear(fields.Year)
+	if err != nil {
+		return err
+	}
+	info.Year = year
+	return nil
+}
+
+// decodeCOCOYear decodes the year of the info, either a number or a string
+// of it, 0 if it is missing or empty
+func decodeCOCOYear(data json.RawMessage) (int, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return 0, nil
+	}
+	if data[0] != '"' {
+		var year int
+		if err := json.Unmarshal(data, &year); err != nil {
+			return 0, fmt.Errorf("the year %v is not a number", string(data))
+		}
+		return year, nil
+	}
+
+	var yearString string
+	if err := json.Unmarshal(data, &yearString); err != nil {
+		return 0, err
+	}
+	yearString = strings.TrimSpace(yearString)
+	if yearString == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(yearString)
+	if err != nil {
+		return 0, fmt.Errorf("the year %v is not a number", string(data))
+	}
+	return year, nil
+}
+
 type COCOLicense struct {
 	ID   int    `json:"id"`
 	URL  string `json:"url"`
@@ -40,16 +110,130 @@ type COCOImage struct {
 	Height       int    `json:"height"`
 	Width        int    `json:"width"`
 	DateCaptured string `json:"date_captured"`
+
+	// the fields beyond the standard ones, e.g. coco_url or video_id
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+var cocoImageFields = map[string]bool{
+	"id":            true,
+	"license":       true,
+	"file_name":     true,
+	"height":        true,
+	"width":         true,
+	"date_captured": true,
+}
+
+func (image *COCOImage) UnmarshalJSON(data []byte) error {
+	type plainImage COCOImage
+	if err := json.Unmarshal(data, (*plainImage)(image)); err != nil {
+		return err
+	}
+
+	extra, err := jsonExtraFields(data, cocoImageFields)
+	image.Extra = extra
+	return err
+}
+
+func (image COCOImage) MarshalJSON() ([]byte, error) {
+	type plainImage COCOImage
+	data, err := json.Marshal(plainImage(image))
+	if err != nil {
+		return nil, err
+	}
+	return appendJSONExtraFields(data, image.Extra)
+}
+
+// COCORLE is the run-length encoded mask of a crowd annotation, the counts
+// are either a list of numbers or the compressed string of the COCO api
+type COCORLE struct {
+	Size   []int           `json:"size"`
+	Counts json.RawMessage `json:"counts"`
+}
+
+// COCOSegmentation holds either a list of polygons or a run-length encoded mask
+type COCOSegmentation struct {
+	Polygons [][]float64
+	RLE      *COCORLE
+}
+
+func (segmentation *COCOSegmentation) UnmarshalJSON(data []byte) error {
+	*segmentation = COCOSegmentation{}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	if data[0] == '{' {
+		segmentation.RLE = &COCORLE{}
+		return json.Unmarshal(data, segmentation.RLE)
+	}
+
+	if err := json.Unmarshal(data, &segmentation.Polygons); err == nil {
+		return nil
+	}
+
+	// a single flat polygon as written by the earlier versions
+	var polygon []float64
+	if err := json.Unmarshal(data, &polygon); err != nil {
+		return fmt.Errorf("the segmentation is neither polygons nor rle: %v", err.Error())
+	}
+	segmentation.Polygons = [][]float64{polygon}
+	return nil
+}
+
+func (segmentation COCOSegmentation) MarshalJSON() ([]byte, error) {
+	if segmentation.RLE != nil {
+		return json.Marshal(segmentation.RLE)
+	}
+	if segmentation.Polygons == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(segmentation.Polygons)
 }
 
 type COCOAnnotation struct {
-	ID           int       `json:"id"`
-	ImageID      int       `json:"image_id"`
-	CategoryID   int       `json:"category_id"`
-	BBox         []float32 `json:"bbox"`
-	Area         float32   `json:"area"`
-	Segmentation []float32 `json:"segmentation"`
-	IsCrowd      int       `json:"iscrowd"`
+	ID           int              `json:"id"`
+	ImageID      int              `json:"image_id"`
+	CategoryID   int              `json:"category_id"`
+	BBox         []float32        `json:"bbox"`
+	Area         float32          `json:"area"`
+	Segmentation COCOSegmentation `json:"segmentation"`
+	IsCrowd      int              `json:"iscrowd"`
+
+	// the fields beyond the standard ones, e.g. the voc difficult flag
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+var cocoAnnotationFields = map[string]bool{
+	"id":           true,
+	"image_id":     true,
+	"category_id":  true,
+	"bbox":         true,
+	"area":         true,
+	"segmentation": true,
+	"iscrowd":      true,
+}
+
+func (annotation *COCOAnnotation) UnmarshalJSON(data []byte) error {
+	type plainAnnotation COCOAnnotation
+	if err := json.Unmarshal(data, (*plainAnnotation)(annotation)); err != nil {
+		return err
+	}
+
+	extra, err := jsonExtraFields(data, cocoAnnotationFields)
+	annotation.Extra = extra
+	return err
+}
+
+func (annotation COCOAnnotation) MarshalJSON() ([]byte, error) {
+	type plainAnnotation COCOAnnotation
+	data, err := json.Marshal(plainAnnotation(annotation))
+	if err != nil {
+		return nil, err
+	}
+	return appendJSONExtraFields(data, annotation.Extra)
 }
 
 type COCOAnnotations struct {
@@ -65,98 +249,163 @@ func ReadCOCOAnnotationsFromFile(annotations *COCOAnnotations, path string) erro
 		return errors.New(path + " is not a valid json file path")
 	}
 
-	jsonBytes, readErr := ioutil.ReadFile(path)
-
-	if readErr != nil {
-		return readErr
+	jsonFile, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer jsonFile.Close()
 
-	xmlStr := string(jsonBytes)
-	return json.Unmarshal([]byte(xmlStr), annotations)
+	return json.NewDecoder(bufio.NewReader(jsonFile)).Decode(annotations)
 }
 
-func ReadCOCOAnnotationsFromPascalVOCDir(annotations *COCOAnnotations, path string) error {
-	// read the voc annotations data from the directory path
-	var vocAnnotations VOCAnnotations
-	if err := ReadVOCAnnotationFromDir(&vocAnnotations, path); err != nil {
-		return err
+// DecodeCOCOAnnotations decodes the coco annotations data into the dataset
+func DecodeCOCOAnnotations(dataset *Dataset, annotations *COCOAnnotations) error {
+	*dataset = Dataset{
+		Info:        decodeCOCOInfo(annotations.Info),
+		Licenses:    make([]DatasetLicense, len(annotations.Licenses)),
+		Categories:  make([]DatasetCategory, len(annotations.Categories)),
+		Images:      make([]DatasetImage, len(annotations.Images)),
+		Annotations: make([]DatasetAnnotation, len(annotations.Annotations)),
 	}
 
-	info := COCOInfo{
-		Year:        "2022",
-		Version:     "1",
-		Description: "Exported from datasetgo",
-		Contributor: "5km@smslit.cn",
-		URL:         "",
-		DateCreated: time.Now().Format("2006-01-02T15:04:05+00:00"),
+	for index, license := range annotations.Licenses {
+		dataset.Licenses[index] = decodeCOCOLicense(license)
 	}
 
-	license := COCOLicense{
-		ID:   1,
-		URL:  "",
-		Name: "5km",
+	for index, category := range annotations.Categories {
+		dataset.Categories[index] = decodeCOCOCategory(category)
 	}
 
-	categoriesMap := make(map[string]COCOCategory)
+	for index, image := range annotations.Images {
+		dataset.Images[index] = decodeCOCOImage(image)
+	}
 
-	images := make([]COCOImage, len(vocAnnotations))
+	for index, annotationItem := range annotations.Annotations {
+		annotation, err := decodeCOCOAnnotation(annotationItem)
+		if err != nil {
+			return err
+		}
+		dataset.Annotations[index] = annotation
+	}
 
-	var annotationItems []COCOAnnotation
+	return nil
+}
+
+func decodeCOCOInfo(info COCOInfo) DatasetInfo {
+	return DatasetInfo{
+		Year:        info.Year,
+		Version:     info.Version,
+		Description: info.Description,
+		Contributor: info.Contributor,
+		URL:         info.URL,
+		DateCreated: info.DateCreated,
+	}
+}
+
+func decodeCOCOLicense(license COCOLicense) DatasetLicense {
+	return DatasetLicense{
+		ID:   license.ID,
+		URL:  license.URL,
+		Name: license.Name,
+	}
+}
 
-	// add new annotation data
-	for index, vocAnnotation := range vocAnnotations {
+func decodeCOCOCategory(category COCOCategory) DatasetCategory {
+	return DatasetCategory{
+		ID:            category.ID,
+		Name:          category.Name,
+		SuperCategory: category.SuperCategory,
+	}
+}
 
-		// add new image info
-		// TODO: get the captured time of image
-		cocoImage := COCOImage{
-			ID:           index + 1,
-			License:      1,
-			FileName:     vocAnnotation.Filename,
-			Height:       vocAnnotation.Size.Height,
-			Width:        vocAnnotation.Size.Width,
-			DateCaptured: "",
+func decodeCOCOImage(image COCOImage) DatasetImage {
+	attributes, jsonAttributes := extraToAttributes(image.Extra)
+	return DatasetImage{
+		ID:             image.ID,
+		License:        image.License,
+		FileName:       image.FileName,
+		Width:          image.Width,
+		Height:         image.Height,
+		DateCaptured:   image.DateCaptured,
+		Attributes:     attributes,
+		JSONAttributes: jsonAttributes,
+	}
+}
+
+// decodeCOCOAnnotation decodes the annotation, the bbox and area are derived
+// from the segmentation when absent
+func decodeCOCOAnnotation(annotationItem COCOAnnotation) (DatasetAnnotation, error) {
+	attributes, jsonAttributes := extraToAttributes(annotationItem.Extra)
+	annotation := DatasetAnnotation{
+		ID:             annotationItem.ID,
+		ImageID:        annotationItem.ImageID,
+		CategoryID:     annotationItem.CategoryID,
+		Area:           float64(annotationItem.Area),
+		Segmentation:   make([]Polygon, 0, len(annotationItem.Segmentation.Polygons)),
+		IsCrowd:        annotationItem.IsCrowd != 0,
+		Attributes:     attributes,
+		JSONAttributes: jsonAttributes,
+	}
+
+	for _, polygon := range annotationItem.Segmentation.Polygons {
+		annotation.Segmentation = append(annotation.Segmentation, Polygon(polygon))
+	}
+	if rle := annotationItem.Segmentation.RLE; rle != nil {
+		mask, err := decodeCOCORLE(rle)
+		if err != nil {
+			return DatasetAnnotation{}, fmt.Errorf("the segmentation of annotation with ID[%v] is invalid: %v", annotationItem.ID, err.Error())
 		}
-		images[index] = cocoImage
-
-		// add new annotation info
-		annotaionsLength := len(annotationItems)
-		for i, obj := range vocAnnotation.Object {
-			// get id of the category, or add new category
-			category, ok := categoriesMap[obj.Name]
-			if !ok {
-				mapLength := len(categoriesMap)
-				category = COCOCategory{
-					ID:            mapLength + 1,
-					Name:          obj.Name,
-					SuperCategory: "",
-				}
-				categoriesMap[obj.Name] = category
-			}
-			boxWidth := float32(obj.Bndbox.Xmax - obj.Bndbox.Xmin)
-			boxHeight := float32(obj.Bndbox.Ymax - obj.Bndbox.Ymin)
-			annotationItem := COCOAnnotation{
-				ID:           annotaionsLength + i,
-				ImageID:      cocoImage.ID,
-				CategoryID:   category.ID,
-				BBox:         []float32{float32(obj.Bndbox.Xmin), float32(obj.Bndbox.Ymin), boxWidth, boxHeight},
-				Area:         boxWidth * boxHeight,
-				Segmentation: make([]float32, 0),
-				IsCrowd:      0,
-			}
-			annotationItems = append(annotationItems, annotationItem)
+		annotation.Mask = mask
+	}
+
+	switch len(annotationItem.BBox) {
+	case 4:
+		annotation.BBox = BoundingBox{
+			X:      float64(annotationItem.BBox[0]),
+			Y:      float64(annotationItem.BBox[1]),
+			Width:  float64(annotationItem.BBox[2]),
+			Height: float64(annotationItem.BBox[3]),
+		}
+	case 0:
+		if annotation.Mask == nil && len(annotation.Segmentation) == 0 {
+			return DatasetAnnotation{}, fmt.Errorf("the annotation with ID[%v] has neither bbox nor segmentation", annotationItem.ID)
 		}
+		annotation.BBox = annotation.SegmentationBBox()
+	default:
+		return DatasetAnnotation{}, fmt.Errorf("the bbox of annotation with ID[%v] must have 4 values", annotationItem.ID)
+	}
+	if annotation.Area == 0 {
+		annotation.Area = annotation.SegmentationArea()
+	}
+	if annotation.Area == 0 {
+		annotation.Area = annotation.BBox.Width * annotation.BBox.Height
+	}
+
+	return annotation, nil
+}
+
+// EncodeCOCOAnnotations encodes the dataset into the coco annotations data
+func EncodeCOCOAnnotations(annotations *COCOAnnotations, dataset *Dataset) error {
+	licenses := encodeCOCOLicenses(dataset)
+
+	categories := make([]COCOCategory, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		categories[index] = encodeCOCOCategory(category)
+	}
 
+	images := make([]COCOImage, len(dataset.Images))
+	for index, image := range dataset.Images {
+		images[index] = encodeCOCOImage(image, licenses[0].ID)
 	}
 
-	// generate category data from map data
-	categories := make([]COCOCategory, len(categoriesMap))
-	for _, category := range categoriesMap {
-		categories[category.ID-1] = category
+	annotationItems := make([]COCOAnnotation, len(dataset.Annotations))
+	for index, annotation := range dataset.Annotations {
+		annotationItems[index] = encodeCOCOAnnotation(annotation)
 	}
 
 	*annotations = COCOAnnotations{
-		Info:        info,
-		Licenses:    []COCOLicense{license},
+		Info:        encodeCOCOInfo(dataset),
+		Licenses:    licenses,
 		Images:      images,
 		Categories:  categories,
 		Annotations: annotationItems,
@@ -165,102 +414,231 @@ func ReadCOCOAnnotationsFromPascalVOCDir(annotations *COCOAnnotations, path stri
 	return nil
 }
 
-func ReadCOCOAnnotationsFromCreateMLFile(annotations *COCOAnnotations, path string) error {
-	var createMLAnnotations CreateMLAnnotations
-	if err := ReadCreateMLAnnotationsFromFile(&createMLAnnotations, path); err != nil {
-		return err
+func encodeCOCOInfo(dataset *Dataset) COCOInfo {
+	info := COCOInfo{
+		Year:        dataset.Info.Year,
+		Version:     dataset.Info.Version,
+		Description: dataset.Info.Description,
+		Contributor: dataset.Info.Contributor,
+		URL:         dataset.Info.URL,
+		DateCreated: dataset.Info.DateCreated,
+	}
+	// no creation date by default so that the same dataset is always
+	// written into the same bytes
+	if info == (COCOInfo{}) {
+		info = COCOInfo{
+			Year:        2022,
+			Version:     "1",
+			Description: "Exported from datasetgo",
+			Contributor: "5km@smslit.cn",
+			URL:         "",
+		}
 	}
+	return info
+}
 
-	info := COCOInfo{
-		Year:        "2022",
-		Version:     "1",
-		Description: "Exported from datasetgo",
-		Contributor: "5km@smslit.cn",
-		URL:         "",
-		DateCreated: time.Now().Format("2006-01-02T15:04:05+00:00"),
+// encodeCOCOLicenses encodes the licenses of the dataset, a default one if
+// the dataset has none
+func encodeCOCOLicenses(dataset *Dataset) []COCOLicense {
+	licenses := make([]COCOLicense, len(dataset.Licenses))
+	for index, license := range dataset.Licenses {
+		licenses[index] = COCOLicense{
+			ID:   license.ID,
+			URL:  license.URL,
+			Name: license.Name,
+		}
 	}
+	if len(licenses) == 0 {
+		licenses = append(licenses, COCOLicense{
+			ID:   1,
+			URL:  "",
+			Name: "5km",
+		})
+	}
+	return licenses
+}
 
-	license := COCOLicense{
-		ID:   1,
-		URL:  "",
-		Name: "5km",
+func encodeCOCOCategory(category DatasetCategory) COCOCategory {
+	return COCOCategory{
+		ID:            category.ID,
+		Name:          category.Name,
+		SuperCategory: category.SuperCategory,
 	}
+}
 
-	categoriesMap := make(map[string]COCOCategory)
+// encodeCOCOImage encodes the image, the image without license gets the
+// default one
+func encodeCOCOImage(image DatasetImage, defaultLicense int) COCOImage {
+	license := image.License
+	if license == 0 {
+		license = defaultLicense
+	}
+	// TODO: get the captured time of image
+	return COCOImage{
+		ID:           image.ID,
+		License:      license,
+		FileName:     image.FileName,
+		Height:       image.Height,
+		Width:        image.Width,
+		DateCaptured: image.DateCaptured,
+		Extra:        attributesToExtra(image.Attributes, image.JSONAttributes),
+	}
+}
 
-	images := make([]COCOImage, len(createMLAnnotations))
+func encodeCOCOAnnotation(annotation DatasetAnnotation) COCOAnnotation {
+	segmentation := COCOSegmentation{
+		Polygons: make([][]float64, 0, len(annotation.Segmentation)),
+	}
+	for _, polygon := range annotation.Segmentation {
+		segmentation.Polygons = append(segmentation.Polygons, polygon)
+	}
+	if annotation.Mask != nil {
+		segmentation.RLE = encodeCOCORLE(annotation.Mask)
+	}
+	area := annotation.Area
+	if area == 0 {
+		area = annotation.SegmentationArea()
+	}
+	if area == 0 {
+		area = annotation.BBox.Width * annotation.BBox.Height
+	}
+	isCrowd := 0
+	if annotation.IsCrowd {
+		isCrowd = 1
+	}
+	return COCOAnnotation{
+		ID:         annotation.ID,
+		ImageID:    annotation.ImageID,
+		CategoryID: annotation.CategoryID,
+		BBox: []float32{
+			float32(annotation.BBox.X),
+			float32(annotation.BBox.Y),
+			float32(annotation.BBox.Width),
+			float32(annotation.BBox.Height),
+		},
+		Area:         float32(area),
+		Segmentation: segmentation,
+		IsCrowd:      isCrowd,
+		Extra:        attributesToExtra(annotation.Attributes, annotation.JSONAttributes),
+	}
+}
 
-	var annotationItems []COCOAnnotation
+// decodeCOCORLE decodes the compressed or uncompressed rle of COCO
+func decodeCOCORLE(rle *COCORLE) (*RLEMask, error) {
+	if len(rle.Size) != 2 {
+		return nil, errors.New("the size of rle must be [height, width]")
+	}
 
-	// add new annotation data
-	for index, createMLAnnotation := range createMLAnnotations {
-		imagePath := filepath.Join(filepath.Dir(path), createMLAnnotation.Image)
-		imageFile, err := os.Open(imagePath)
-		if err != nil {
-			return err
-		}
-		defer imageFile.Close()
-		imageConfig, _, err := image.DecodeConfig(imageFile)
+	mask := &RLEMask{
+		Height: rle.Size[0],
+		Width:  rle.Size[1],
+	}
+
+	var compressed string
+	if err := json.Unmarshal(rle.Counts, &compressed); err == nil {
+		counts, err := DecodeRLECounts(compressed)
 		if err != nil {
-			return err
-		}
-		// add new image info
-		// TODO: get the captured time of image
-		cocoImage := COCOImage{
-			ID:           index + 1,
-			License:      1,
-			FileName:     createMLAnnotation.Image,
-			Height:       imageConfig.Height,
-			Width:        imageConfig.Width,
-			DateCaptured: "",
-		}
-		images[index] = cocoImage
-
-		// add new annotation info
-		annotaionsLength := len(annotationItems)
-		for i, createMLAnnotationItem := range createMLAnnotation.Annotations {
-			// get id of the category, or add new category
-			category, ok := categoriesMap[createMLAnnotationItem.Label]
-			if !ok {
-				mapLength := len(categoriesMap)
-				category = COCOCategory{
-					ID:            mapLength + 1,
-					Name:          createMLAnnotationItem.Label,
-					SuperCategory: "",
-				}
-				categoriesMap[createMLAnnotationItem.Label] = category
-			}
-			annotationItem := COCOAnnotation{
-				ID:           annotaionsLength + i,
-				ImageID:      cocoImage.ID,
-				CategoryID:   category.ID,
-				BBox:         []float32{createMLAnnotationItem.Coordinates.X, createMLAnnotationItem.Coordinates.Y, createMLAnnotationItem.Coordinates.Width, createMLAnnotationItem.Coordinates.Height},
-				Area:         createMLAnnotationItem.Coordinates.Width * createMLAnnotationItem.Coordinates.Height,
-				Segmentation: make([]float32, 0),
-				IsCrowd:      0,
-			}
-			annotationItems = append(annotationItems, annotationItem)
+			return nil, err
 		}
+		mask.Counts = counts
+		mask.Compressed = true
+		return mask, nil
+	}
 
+	if err := json.Unmarshal(rle.Counts, &mask.Counts); err != nil {
+		return nil, errors.New("the counts of rle must be a string or a list of numbers")
 	}
+	return mask, nil
+}
 
-	// generate category data from map data
-	categories := make([]COCOCategory, len(categoriesMap))
-	for _, category := range categoriesMap {
-		categories[category.ID-1] = category
+// encodeCOCORLE encodes the mask as COCO rle, in the form it was read
+func encodeCOCORLE(mask *RLEMask) *COCORLE {
+	var counts interface{} = mask.Counts
+	if mask.Compressed {
+		counts = EncodeRLECounts(mask.Counts)
 	}
 
-	*annotations = COCOAnnotations{
-		Info:        info,
-		Licenses:    []COCOLicense{license},
-		Images:      images,
-		Categories:  categories,
-		Annotations: annotationItems,
+	// marshaling a string or a list of numbers never fails
+	countsBytes, _ := json.Marshal(counts)
+	return &COCORLE{
+		Size:   []int{mask.Height, mask.Width},
+		Counts: countsBytes,
+	}
+}
+
+// ReadDatasetFromCOCOFile reads the dataset from the coco json file, the
+// json is decoded as a stream so it is never held in memory besides the
+// dataset
+func ReadDatasetFromCOCOFile(dataset *Dataset, path string) error {
+	return readDatasetFromOS(dataset, path, ReadDatasetFromCOCOFS)
+}
+
+// ReadDatasetFromCOCOFS reads the dataset from the coco json file in the
+// file system, the image file names are relative to the directory of the file
+func ReadDatasetFromCOCOFS(dataset *Dataset, fsys fs.FS, name string) error {
+	if nameExt := path.Ext(name); nameExt == "" || strings.ToLower(nameExt) != ".json" {
+		return errors.New(fsPath(fsys, name) + " is not a valid json file path")
 	}
 
+	jsonFile, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer jsonFile.Close()
+
+	if err := DecodeCOCOStream(dataset, bufio.NewReader(jsonFile)); err != nil {
+		return fmt.Errorf("%v reading... %v", fsPath(fsys, name), err.Error())
+	}
+	dataset.ImageDir = path.Dir(name)
+	dataset.ImageFS = fsys
 	return nil
 }
 
+// WriteDatasetToCOCOFile writes the dataset to the coco json file, the json
+// is encoded as a stream
+func WriteDatasetToCOCOFile(dataset *Dataset, path string) error {
+	if pathExt := filepath.Ext(path); pathExt == "" || strings.ToLower(pathExt) != ".json" {
+		return errors.New(path + " is not a valid json file path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	jsonFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeCOCOStream(jsonFile, dataset); err != nil {
+		jsonFile.Close()
+		return err
+	}
+	return jsonFile.Close()
+}
+
+// cocoWriter writes coco json files, the images are placed in images/ next
+// to the file
+type cocoWriter struct{}
+
+func (cocoWriter) WriteDataset(dataset *Dataset, path string) error {
+	return WriteDatasetToCOCOFile(dataset, path)
+}
+
+func (cocoWriter) ImageDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "images")
+}
+
+func (cocoWriter) WriteDatasetLayout(dataset *Dataset, path string) error {
+	// the file names are relative to the directory of the json file
+	prefixed := *dataset
+	prefixed.Images = make([]DatasetImage, len(dataset.Images))
+	for index, image := range dataset.Images {
+		image.FileName = "images/" + image.FileName
+		prefixed.Images[index] = image
+	}
+	prefixed.ImageDir = filepath.Dir(path)
+	return WriteDatasetToCOCOFile(&prefixed, path)
+}
+
 func WriteCOCOAnnotationsToFile(annotations *COCOAnnotations, path string) error {
 	annotationsBytes, err := json.MarshalIndent(*annotations, "", "    ")
 	if err != nil {
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCOCOInstances(t *testing.T) {
+	var dataset Dataset
+	if err := ReadDatasetFromCOCOFile(&dataset, "testdata/coco_instances.json"); err != nil {
+		t.Fatal(err)
+	}
+	if dataset.Info.Year != 2017 {
+		t.Errorf("year = %v, want 2017", dataset.Info.Year)
+	}
+	if len(dataset.Images) != 2 || len(dataset.Annotations) != 2 || len(dataset.Categories) != 2 {
+		t.Fatalf("read %v images, %v annotations and %v categories, want 2 of each", len(dataset.Images), len(dataset.Annotations), len(dataset.Categories))
+	}
+
+	var written bytes.Buffer
+	if err := EncodeCOCOStream(&written, &dataset); err != nil {
+		t.Fatal(err)
+	}
+	var fields struct {
+		Info map[string]json.RawMessage `json:"info"`
+	}
+	if err := json.Unmarshal(written.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if year := string(fields.Info["year"]); year != "2017" {
+		t.Errorf("written year = %v, want the number 2017", year)
+	}
+}
+
+func TestCOCOInfoYear(t *testing.T) {
+	years := []struct {
+		data string
+		want int
+	}{
+		{`{"year": 2017}`, 2017},
+		{`{"year": "2022"}`, 2022},
+		{`{"year": ""}`, 0},
+		{`{"year": null}`, 0},
+		{`{}`, 0},
+	}
+	for _, year := range years {
+		var info COCOInfo
+		if err := json.Unmarshal([]byte(year.data), &info); err != nil {
+			t.Errorf("%v: %v", year.data, err)
+		} else if info.Year != year.want {
+			t.Errorf("%v: year = %v, want %v", year.data, info.Year, year.want)
+		}
+	}
+
+	var info COCOInfo
+	if err := json.Unmarshal([]byte(`{"year": "twenty"}`), &info); err == nil {
+		t.Error("the year twenty is decoded")
+	}
+}
+
+func TestWriteJSONIntoNewDirectory(t *testing.T) {
+	var dataset Dataset
+	if err := ReadDatasetFromCOCOFile(&dataset, filepath.Join("testdata", "coco.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writers := map[string]func(dataset *Dataset, path string) error{
+		"coco":     WriteDatasetToCOCOFile,
+		"createml": WriteDatasetToCreateMLFile,
+	}
+	for name, write := range writers {
+		path := filepath.Join(dir, name, "annotations", "dataset.json")
+		if err := write(&dataset, path); err != nil {
+			t.Errorf("writing %v: %v", name, err)
+		} else if _, err := os.Stat(path); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeCOCOStream decodes the coco json read from r into the dataset one
+// image and annotation at a time, the json is never held in memory as a whole
+// so the memory needed is about that of the dataset. The top-level fields may
+// come in any order and the unknown ones are skipped.
+func DecodeCOCOStream(dataset *Dataset, r io.Reader) error {
+	*dataset = Dataset{
+		Licenses:    make([]DatasetLicense, 0),
+		Categories:  make([]DatasetCategory, 0),
+		Images:      make([]DatasetImage, 0),
+		Annotations: make([]DatasetAnnotation, 0),
+	}
+
+	return DecodeCOCORecords(r, DatasetRecords{
+		Info: func(info DatasetInfo) error {
+			dataset.Info = info
+			return nil
+		},
+		License: func(license DatasetLicense) error {
+			dataset.Licenses = append(dataset.Licenses, license)
+			return nil
+		},
+		Category: func(category DatasetCategory) error {
+			dataset.Categories = append(dataset.Categories, category)
+			return nil
+		},
+		Image: func(image DatasetImage) error {
+			dataset.Images = append(dataset.Images, image)
+			return nil
+		},
+		Annotation: func(annotation DatasetAnnotation) error {
+			dataset.Annotations = append(dataset.Annotations, annotation)
+			return nil
+		},
+	})
+}
+
+// DecodeCOCORecords decodes the coco json read from r and passes every record
+// to records in the order of the json without keeping any, the memory needed
+// is that of the largest record. The arrays without a function are skipped
+// undecoded.
+func DecodeCOCORecords(r io.Reader, records DatasetRecords) error {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return errors.New("the coco json must be an object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+
+		switch {
+		case key == "info" && records.Info != nil:
+			var info COCOInfo
+			if err = decoder.Decode(&info); err == nil {
+				err = records.Info(decodeCOCOInfo(info))
+			}
+		case key == "licenses" && records.License != nil:
+			err = decodeJSONArray(decoder, func() error {
+				var license COCOLicense
+				if err := decoder.Decode(&license); err != nil {
+					return err
+				}
+				return records.License(decodeCOCOLicense(license))
+			})
+		case key == "categories" && records.Category != nil:
+			err = decodeJSONArray(decoder, func() error {
+				var category COCOCategory
+				if err := decoder.Decode(&category); err != nil {
+					return err
+				}
+				return records.Category(decodeCOCOCategory(category))
+			})
+		case key == "images" && records.Image != nil:
+			err = decodeJSONArray(decoder, func() error {
+				var image COCOImage
+				if err := decoder.Decode(&image); err != nil {
+					return err
+				}
+				return records.Image(decodeCOCOImage(image))
+			})
+		case key == "annotations" && records.Annotation != nil:
+			err = decodeJSONArray(decoder, func() error {
+				var annotationItem COCOAnnotation
+				if err := decoder.Decode(&annotationItem); err != nil {
+					return err
+				}
+				annotation, err := decodeCOCOAnnotation(annotationItem)
+				if err != nil {
+					return err
+				}
+				return records.Annotation(annotation)
+			})
+		default:
+			err = skipJSONValue(decoder)
+		}
+		if err != nil {
+			return fmt.Errorf("the %v of coco json at offset %d: %v", key, decoder.InputOffset(), err.Error())
+		}
+	}
+
+	_, err := decoder.Token()
+	return err
+}
+
+// decodeJSONArray calls decodeElement for every element of the next json
+// array of the decoder, a null array has no element
+func decodeJSONArray(decoder *json.Decoder, decodeElement func() error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return errors.New("the value must be an array")
+	}
+
+	for decoder.More() {
+		if err := decodeElement(); err != nil {
+			return err
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+// EncodeCOCOStream encodes the dataset as coco json to w one image and
+// annotation at a time, in the same bytes as WriteCOCOAnnotationsToFile
+// without building the whole json in memory
+func EncodeCOCOStream(w io.Writer, dataset *Dataset) error {
+	licenses := encodeCOCOLicenses(dataset)
+
+	encoder := cocoStreamEncoder{writer: bufio.NewWriter(w)}
+	encoder.writeString("{\n    \"info\": ")
+	encoder.writeValue(encodeCOCOInfo(dataset), "    ")
+	encoder.writeArray("licenses", len(licenses), func(index int) interface{} {
+		return licenses[index]
+	})
+	encoder.writeArray("categories", len(dataset.Categories), func(index int) interface{} {
+		return encodeCOCOCategory(dataset.Categories[index])
+	})
+	encoder.writeArray("images", len(dataset.Images), func(index int) interface{} {
+		return encodeCOCOImage(dataset.Images[index], licenses[0].ID)
+	})
+	encoder.writeArray("annotations", len(dataset.Annotations), func(index int) interface{} {
+		return encodeCOCOAnnotation(dataset.Annotations[index])
+	})
+	encoder.writeString("\n}")
+
+	if encoder.err != nil {
+		return encoder.err
+	}
+	return encoder.writer.Flush()
+}
+
+// cocoStreamEncoder writes the json indented by 4 spaces as MarshalIndent
+// does, the first error stops the writing
+type cocoStreamEncoder struct {
+	writer *bufio.Writer
+	buffer bytes.Buffer
+	err    error
+}
+
+func (encoder *cocoStreamEncoder) writeString(s string) {
+	if encoder.err == nil {
+		_, encoder.err = encoder.writer.WriteString(s)
+	}
+}
+
+// writeValue writes the value indented under the prefix, the first line is
+// not prefixed
+func (encoder *cocoStreamEncoder) writeValue(value interface{}, prefix string) {
+	if encoder.err != nil {
+		return
+	}
+
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		encoder.err = err
+		return
+	}
+	encoder.buffer.Reset()
+	if encoder.err = json.Indent(&encoder.buffer, valueBytes, prefix, "    "); encoder.err == nil {
+		_, encoder.err = encoder.buffer.WriteTo(encoder.writer)
+	}
+}
+
+// writeArray writes a field of the top-level object with the elements
+// returned by element
+func (encoder *cocoStreamEncoder) writeArray(key string, count int, element func(index int) interface{}) {
+	encoder.writeString(",\n    \"" + key + "\": ")
+	if count == 0 {
+		encoder.writeString("[]")
+		return
+	}
+
+	encoder.writeString("[\n")
+	for index := 0; index < count && encoder.err == nil; index++ {
+		encoder.writeString("        ")
+		encoder.writeValue(element(index), "        ")
+		if index < count-1 {
+			encoder.writeString(",\n")
+		}
+	}
+	encoder.writeString("\n    ]")
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCOCOStreamMatchesBatch(t *testing.T) {
+	var annotations COCOAnnotations
+	if err := ReadCOCOAnnotationsFromFile(&annotations, "testdata/coco.json"); err != nil {
+		t.Fatal(err)
+	}
+	var want Dataset
+	if err := DecodeCOCOAnnotations(&want, &annotations); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Dataset
+	if err := ReadDatasetFromCOCOFile(&got, "testdata/coco.json"); err != nil {
+		t.Fatal(err)
+	}
+	got.ImageDir = ""
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed dataset = %+v, want %+v", got, want)
+	}
+
+	checkCOCOStreamEncoding(t, &want)
+	checkCOCOStreamEncoding(t, &Dataset{})
+}
+
+// checkCOCOStreamEncoding compares the streamed json of the dataset with the
+// json marshaled at once
+func checkCOCOStreamEncoding(t *testing.T, dataset *Dataset) {
+	t.Helper()
+
+	var annotations COCOAnnotations
+	if err := EncodeCOCOAnnotations(&annotations, dataset); err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.MarshalIndent(annotations, "", "    ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got bytes.Buffer
+	if err := EncodeCOCOStream(&got, dataset); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("streamed json:\n%s\nwant:\n%s", got.Bytes(), want)
+	}
+}
+
+func TestDecodeCOCOStreamSkipsUnknownFields(t *testing.T) {
+	const data = `{
+		"annotations": [{"id": 1, "image_id": 1, "category_id": 1, "bbox": [1, 2, 3, 4]}],
+		"extra": {"nested": [1, {"a": []}]},
+		"licenses": null,
+		"images": [{"id": 1, "file_name": "a.jpg", "width": 10, "height": 10}],
+		"categories": [{"id": 1, "name": "car"}]
+	}`
+
+	var dataset Dataset
+	if err := DecodeCOCOStream(&dataset, strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(dataset.Images) != 1 || len(dataset.Annotations) != 1 || len(dataset.Categories) != 1 {
+		t.Fatalf("got %d images, %d annotations and %d categories, want 1 of each",
+			len(dataset.Images), len(dataset.Annotations), len(dataset.Categories))
+	}
+	if area := dataset.Annotations[0].Area; area != 12 {
+		t.Errorf("area = %v, want 12", area)
+	}
+
+	if err := DecodeCOCOStream(&dataset, strings.NewReader(`{"images": {}}`)); err == nil {
+		t.Error("images of an object decoded without error")
+	}
+}
+
+func TestSummarizeCOCORecords(t *testing.T) {
+	// the categories of the coco datasets come after the annotations
+	for _, name := range []string{"testdata/coco.json", "testdata/coco_instances.json"} {
+		var dataset Dataset
+		if err := ReadDatasetFromCOCOFile(&dataset, name); err != nil {
+			t.Fatal(err)
+		}
+		want := SummarizeDataset(&dataset)
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		summarizer := NewDatasetSummarizer()
+		if err := DecodeCOCORecords(bytes.NewReader(data), summarizer.Records()); err != nil {
+			t.Fatal(err)
+		}
+		if got := summarizer.Summary(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v summarized by records = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestDecodeCOCORecordsSkipsArraysWithoutFunction(t *testing.T) {
+	// the annotations are not even decoded without a function for them
+	const data = `{
+		"images": [{"id": 1, "file_name": "a.jpg", "width": 10, "height": 10}],
+		"annotations": [{"id": 1, "bbox": "not a box"}]
+	}`
+
+	var images []DatasetImage
+	err := DecodeCOCORecords(strings.NewReader(data), DatasetRecords{
+		Image: func(image DatasetImage) error {
+			images = append(images, image)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 || images[0].FileName != "a.jpg" {
+		t.Errorf("images = %+v, want a.jpg", images)
+	}
+}
@@ -1,24 +1,72 @@
 package model
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
+func init() {
+	MustRegisterFormat(&Format{
+		Name:        "createml",
+		Description: "Create ML(apple)",
+		Extension:   ".json",
+		Detect:      detectFromOS(detectCreateMLFile),
+		Reader:      DatasetReaderFunc(ReadDatasetFromCreateMLFile),
+		Writer:      createMLWriter{},
+		DetectFS:    detectCreateMLFile,
+		FSReader:    DatasetFSReaderFunc(ReadDatasetFromCreateMLFS),
+		Encoder:     DatasetEncoderFunc(EncodeCreateMLStream),
+	})
+}
+
+// CreateMLCoordinates is the box of an object in absolute pixels, as Create ML
+// does (X, Y) is the center of the box rather than its top-left corner
 type CreateMLCoordinates struct {
 	X      float32 `json:"x"`
 	Y      float32 `json:"y"`
 	Width  float32 `json:"width"`
-	Height float32 `json:"Height"`
+	Height float32 `json:"height"`
 }
 
 type CreateMLAnnotationItem struct {
 	Label       string              `json:"label"`
 	Coordinates CreateMLCoordinates `json:"coordinates"`
+
+	// the fields beyond the standard ones, e.g. the voc difficult flag
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+var createMLAnnotationItemFields = map[string]bool{
+	"label":       true,
+	"coordinates": true,
+}
+
+func (item *CreateMLAnnotationItem) UnmarshalJSON(data []byte) error {
+	type plainItem CreateMLAnnotationItem
+	if err := json.Unmarshal(data, (*plainItem)(item)); err != nil {
+		return err
+	}
+
+	extra, err := jsonExtraFields(data, createMLAnnotationItemFields)
+	item.Extra = extra
+	return err
+}
+
+func (item CreateMLAnnotationItem) MarshalJSON() ([]byte, error) {
+	type plainItem CreateMLAnnotationItem
+	data, err := json.Marshal(plainItem(item))
+	if err != nil {
+		return nil, err
+	}
+	return appendJSONExtraFields(data, item.Extra)
 }
 
 type CreateMLAnnotation struct {
@@ -29,114 +77,174 @@ type CreateMLAnnotation struct {
 type CreateMLAnnotations []CreateMLAnnotation
 
 func ReadCreateMLAnnotationsFromFile(annotations *CreateMLAnnotations, path string) error {
-	if pathExt := filepath.Ext(path); pathExt == "" || strings.ToLower(pathExt) != ".json" {
-		return errors.New(path + " is not a valid json file path")
-	}
+	fsys, name := osPathFS(path)
+	return readCreateMLAnnotationsFS(annotations, fsys, name)
+}
 
-	jsonBytes, readErr := ioutil.ReadFile(path)
+func readCreateMLAnnotationsFS(annotations *CreateMLAnnotations, fsys fs.FS, name string) error {
+	if nameExt := path.Ext(name); nameExt == "" || strings.ToLower(nameExt) != ".json" {
+		return errors.New(fsPath(fsys, name) + " is not a valid json file path")
+	}
 
-	if readErr != nil {
-		return readErr
+	jsonFile, err := fsys.Open(name)
+	if err != nil {
+		return err
 	}
+	defer jsonFile.Close()
 
-	xmlStr := string(jsonBytes)
-	return json.Unmarshal([]byte(xmlStr), annotations)
+	return json.NewDecoder(bufio.NewReader(jsonFile)).Decode(annotations)
 }
 
-func ReadCreateMLAnnotationsFromPascalVOCDir(annotations *CreateMLAnnotations, path string) error {
-	// read the voc annotations data from the directory path
-	var vocAnnotations VOCAnnotations
-	if err := ReadVOCAnnotationFromDir(&vocAnnotations, path); err != nil {
+// DecodeCreateMLAnnotations decodes the createml annotations data into the
+// dataset, the image sizes are read from the images under imageDir
+func DecodeCreateMLAnnotations(dataset *Dataset, annotations *CreateMLAnnotations, imageDir string) error {
+	if err := decodeCreateMLAnnotations(dataset, annotations, osDirFS(imageDir), "."); err != nil {
 		return err
 	}
+	dataset.ImageDir = imageDir
+	dataset.ImageFS = nil
+	return nil
+}
 
-	for _, vocAnnotation := range vocAnnotations {
-		annotationItems := []CreateMLAnnotationItem{}
-		for _, vocObject := range vocAnnotation.Object {
-			x := float32(vocObject.Bndbox.Xmin)
-			y := float32(vocObject.Bndbox.Ymin)
-			width := float32(vocObject.Bndbox.Xmax - vocObject.Bndbox.Xmin)
-			height := float32(vocObject.Bndbox.Ymax - vocObject.Bndbox.Ymin)
-			annotationItem := CreateMLAnnotationItem{
-				Label: vocObject.Name,
-				Coordinates: CreateMLCoordinates{
-					X:      x,
-					Y:      y,
-					Width:  width,
-					Height: height,
-				},
-			}
-			annotationItems = append(annotationItems, annotationItem)
-		}
+// decodeCreateMLAnnotations decodes the createml annotations data into the
+// dataset, the image sizes are read from the images under imageDir of the
+// file system
+func decodeCreateMLAnnotations(dataset *Dataset, annotations *CreateMLAnnotations, fsys fs.FS, imageDir string) error {
+	*dataset = Dataset{
+		Categories:  make([]DatasetCategory, 0),
+		Images:      make([]DatasetImage, 0, len(*annotations)),
+		Annotations: make([]DatasetAnnotation, 0),
+		ImageDir:    imageDir,
+		ImageFS:     fsys,
+	}
 
-		annotation := CreateMLAnnotation{
-			Image:       vocAnnotation.Filename,
-			Annotations: annotationItems,
-		}
+	// the image headers are read with LoadJobs workers
+	sizes := make([][2]int, len(*annotations))
+	err := loadParallel(len(*annotations), func(index int) error {
+		width, height, err := readImageSizeFS(fsys, path.Join(imageDir, (*annotations)[index].Image))
+		sizes[index] = [2]int{width, height}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for index, createMLAnnotation := range *annotations {
+		imageID := dataset.appendImage(DatasetImage{
+			FileName: createMLAnnotation.Image,
+			Width:    sizes[index][0],
+			Height:   sizes[index][1],
+		})
 
-		*annotations = append(*annotations, annotation)
+		for _, createMLAnnotationItem := range createMLAnnotation.Annotations {
+			coordinates := createMLAnnotationItem.Coordinates
+			attributes, jsonAttributes := extraToAttributes(createMLAnnotationItem.Extra)
+			dataset.appendAnnotation(DatasetAnnotation{
+				ImageID:    imageID,
+				CategoryID: dataset.AddCategory(createMLAnnotationItem.Label),
+				BBox: BoundingBox{
+					X:      float64(coordinates.X) - float64(coordinates.Width)/2,
+					Y:      float64(coordinates.Y) - float64(coordinates.Height)/2,
+					Width:  float64(coordinates.Width),
+					Height: float64(coordinates.Height),
+				},
+				Area:           float64(coordinates.Width * coordinates.Height),
+				Segmentation:   make([]Polygon, 0),
+				Attributes:     attributes,
+				JSONAttributes: jsonAttributes,
+			})
+		}
 	}
 
 	return nil
 }
 
-func ReadCreateMLAnnotationsFromCOCOFile(annotations *CreateMLAnnotations, path string) error {
+// EncodeCreateMLAnnotations encodes the dataset into the createml annotations data
+func EncodeCreateMLAnnotations(annotations *CreateMLAnnotations, dataset *Dataset) error {
+	categoryMap := dataset.CategoryMap()
+	annotationMap := dataset.AnnotationsByImage()
 
-	// read the coco annotation data from json file
-	var cocoAnnotations COCOAnnotations
-	if err := ReadCOCOAnnotationsFromFile(&cocoAnnotations, path); err != nil {
-		return err
+	imageMap := dataset.ImageMap()
+	for _, annotation := range dataset.Annotations {
+		if _, ok := imageMap[annotation.ImageID]; !ok {
+			return &DanglingReferenceError{Kind: ReferenceImage, ID: annotation.ImageID, AnnotationID: annotation.ID}
+		}
 	}
 
-	// generate the image map with ID
-	imageMap := make(map[int]COCOImage)
-	annotationMap := make(map[int]CreateMLAnnotation)
-	for _, image := range cocoAnnotations.Images {
-		imageMap[image.ID] = image
+	*annotations = make(CreateMLAnnotations, 0, len(dataset.Images))
+	for _, image := range dataset.Images {
 		createMLAnnotation := CreateMLAnnotation{
 			Image:       image.FileName,
 			Annotations: make([]CreateMLAnnotationItem, 0),
 		}
-		annotationMap[image.ID] = createMLAnnotation
+
+		for _, annotation := range annotationMap[image.ID] {
+			category, ok := categoryMap[annotation.CategoryID]
+			if !ok {
+				return &DanglingReferenceError{Kind: ReferenceCategory, ID: annotation.CategoryID, AnnotationID: annotation.ID}
+			}
+			createMLAnnotationItem := CreateMLAnnotationItem{
+				Label: category.Name,
+				Coordinates: CreateMLCoordinates{
+					X:      float32(annotation.BBox.X + annotation.BBox.Width/2),
+					Y:      float32(annotation.BBox.Y + annotation.BBox.Height/2),
+					Width:  float32(annotation.BBox.Width),
+					Height: float32(annotation.BBox.Height),
+				},
+				Extra: attributesToExtra(annotation.Attributes, annotation.JSONAttributes),
+			}
+			createMLAnnotation.Annotations = append(createMLAnnotation.Annotations, createMLAnnotationItem)
+		}
+
+		*annotations = append(*annotations, createMLAnnotation)
 	}
 
-	// generate the category map with ID
-	categoryMap := make(map[int]COCOCategory)
-	for _, category := range cocoAnnotations.Categories {
-		categoryMap[category.ID] = category
+	return nil
+}
+
+// ReadDatasetFromCreateMLFile reads the dataset from the createml json file
+func ReadDatasetFromCreateMLFile(dataset *Dataset, path string) error {
+	return readDatasetFromOS(dataset, path, ReadDatasetFromCreateMLFS)
+}
+
+// ReadDatasetFromCreateMLFS reads the dataset from the createml json file in
+// the file system, the images are next to the file
+func ReadDatasetFromCreateMLFS(dataset *Dataset, fsys fs.FS, name string) error {
+	var annotations CreateMLAnnotations
+	if err := readCreateMLAnnotationsFS(&annotations, fsys, name); err != nil {
+		return err
 	}
 
-	// make the voc annotation data from coco annotations data
-	for _, annotationItem := range cocoAnnotations.Annotations {
-		// check VOVAnnotation data of the image if exists
-		creatMLAnnotation, ok := annotationMap[annotationItem.ImageID]
-		if !ok {
-			return fmt.Errorf("the image with ID[%v] does not exist(annotation with ID[%v])", annotationItem.ImageID, annotationItem.ID)
-		}
+	return decodeCreateMLAnnotations(dataset, &annotations, fsys, path.Dir(name))
+}
 
-		category, ok := categoryMap[annotationItem.CategoryID]
-		if !ok {
-			return fmt.Errorf("the category with ID[%v] does not exist(annotation with ID[%v])", annotationItem.CategoryID, annotationItem.ID)
-		}
-		// convert the coco annotation data to voc annotation data
-		createMLAnnotationItem := CreateMLAnnotationItem{
-			Label: category.Name,
-			Coordinates: CreateMLCoordinates{
-				X:      annotationItem.BBox[0],
-				Y:      annotationItem.BBox[1],
-				Width:  annotationItem.BBox[2],
-				Height: annotationItem.BBox[3],
-			},
-		}
-		creatMLAnnotation.Annotations = append(creatMLAnnotation.Annotations, createMLAnnotationItem)
-		annotationMap[annotationItem.ImageID] = creatMLAnnotation
+// WriteDatasetToCreateMLFile writes the dataset to the createml json file
+func WriteDatasetToCreateMLFile(dataset *Dataset, path string) error {
+	if pathExt := filepath.Ext(path); pathExt == "" || strings.ToLower(pathExt) != ".json" {
+		return errors.New(path + " is not a valid json file path")
 	}
 
-	for _, annotation := range annotationMap {
-		*annotations = append(*annotations, annotation)
+	var annotations CreateMLAnnotations
+	if err := EncodeCreateMLAnnotations(&annotations, dataset); err != nil {
+		return err
 	}
+	return WriteCreateMLAnnotationsToFile(&annotations, path)
+}
 
-	return nil
+// createMLWriter writes createml json files, the images are placed next to
+// the file
+type createMLWriter struct{}
+
+func (createMLWriter) WriteDataset(dataset *Dataset, path string) error {
+	return WriteDatasetToCreateMLFile(dataset, path)
+}
+
+func (createMLWriter) ImageDir(path string) string {
+	return filepath.Dir(path)
+}
+
+func (createMLWriter) WriteDatasetLayout(dataset *Dataset, path string) error {
+	return WriteDatasetToCreateMLFile(dataset, path)
 }
 
 func WriteCreateMLAnnotationsToFile(annotations *CreateMLAnnotations, path string) error {
@@ -145,8 +253,26 @@ func WriteCreateMLAnnotationsToFile(annotations *CreateMLAnnotations, path strin
 		return err
 	}
 
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
 	if writeErr := ioutil.WriteFile(path, annotationsBytes, 0666); writeErr != nil {
 		return writeErr
 	}
 	return nil
 }
+
+// EncodeCreateMLStream encodes the dataset as createml json to w
+func EncodeCreateMLStream(w io.Writer, dataset *Dataset) error {
+	var annotations CreateMLAnnotations
+	if err := EncodeCreateMLAnnotations(&annotations, dataset); err != nil {
+		return err
+	}
+
+	annotationsBytes, err := json.MarshalIndent(annotations, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(annotationsBytes)
+	return err
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// checkGolden compares the file written by the test with the golden file
+func checkGolden(t *testing.T, path string, golden string) {
+	t.Helper()
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile(golden, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%v differs from %v:\n%s", path, golden, got)
+	}
+}
+
+func TestCreateMLCoordinatesAreCenters(t *testing.T) {
+	var annotations CreateMLAnnotations
+	if err := ReadCreateMLAnnotationsFromFile(&annotations, filepath.Join("testdata", "voc.createml.golden.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the car of a.jpg is xmin 11, xmax 50, ymin 20, ymax 63 in voc
+	want := CreateMLCoordinates{X: 30.5, Y: 41.5, Width: 39, Height: 43}
+	if got := annotations[0].Annotations[0].Coordinates; got != want {
+		t.Errorf("coordinates = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateMLImagesOutsideTheJSONDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestJPEG(t, filepath.Join(dir, "images", "a.jpg"), 100, 80)
+	path := filepath.Join(dir, "annotations", "a.json")
+	writeTestFiles(t, dir, map[string]string{
+		"annotations/a.json": `[{"image": "../images/a.jpg", "annotations": [
+			{"label": "car", "coordinates": {"x": 30, "y": 40, "width": 20, "height": 10}}
+		]}]`,
+	})
+
+	var dataset Dataset
+	if err := ReadDatasetFromCreateMLFile(&dataset, path); err != nil {
+		t.Fatal(err)
+	}
+	image := dataset.Images[0]
+	if image.Width != 100 || image.Height != 80 {
+		t.Errorf("size of %v = %vx%v, want 100x80", image.FileName, image.Width, image.Height)
+	}
+	if got, want := dataset.ImagePath(&image), filepath.Join(dir, "images", "a.jpg"); filepath.Clean(got) != want {
+		t.Errorf("image path = %v, want %v", got, want)
+	}
+}
+
+func TestVOCToCreateMLRoundTrip(t *testing.T) {
+	var vocAnnotations VOCAnnotations
+	if err := ReadVOCAnnotationFromDir(&vocAnnotations, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+	var dataset Dataset
+	if err := ReadDatasetFromPascalVOCDir(&dataset, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+
+	createMLPath := filepath.Join(t.TempDir(), "voc.createml.json")
+	if err := WriteDatasetToCreateMLFile(&dataset, createMLPath); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, createMLPath, filepath.Join("testdata", "voc.createml.golden.json"))
+
+	var createMLAnnotations CreateMLAnnotations
+	if err := ReadCreateMLAnnotationsFromFile(&createMLAnnotations, createMLPath); err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip Dataset
+	if err := DecodeCreateMLAnnotations(&roundTrip, &createMLAnnotations, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+	var got VOCAnnotations
+	if err := EncodeVOCAnnotations(&got, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(vocAnnotations) {
+		t.Fatalf("got %v annotations, want %v", len(got), len(vocAnnotations))
+	}
+	for index, annotation := range vocAnnotations {
+		if got[index].Filename != annotation.Filename || got[index].Size != annotation.Size {
+			t.Errorf("image %v = %v %+v, want %v %+v", index, got[index].Filename, got[index].Size, annotation.Filename, annotation.Size)
+		}
+		if len(got[index].Object) != len(annotation.Object) {
+			t.Fatalf("image %v has %v objects, want %v", annotation.Filename, len(got[index].Object), len(annotation.Object))
+		}
+		for i, obj := range annotation.Object {
+			if got[index].Object[i].Name != obj.Name || got[index].Object[i].Bndbox != obj.Bndbox {
+				t.Errorf("object %v of %v = %v %+v, want %v %+v", i, annotation.Filename, got[index].Object[i].Name, got[index].Object[i].Bndbox, obj.Name, obj.Bndbox)
+			}
+		}
+	}
+}
+
+func TestCOCOToCreateMLRoundTrip(t *testing.T) {
+	var cocoAnnotations COCOAnnotations
+	if err := ReadCOCOAnnotationsFromFile(&cocoAnnotations, filepath.Join("testdata", "coco.json")); err != nil {
+		t.Fatal(err)
+	}
+	var dataset Dataset
+	if err := ReadDatasetFromCOCOFile(&dataset, filepath.Join("testdata", "coco.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	createMLPath := filepath.Join(t.TempDir(), "coco.createml.json")
+	if err := WriteDatasetToCreateMLFile(&dataset, createMLPath); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, createMLPath, filepath.Join("testdata", "coco.createml.golden.json"))
+
+	var createMLAnnotations CreateMLAnnotations
+	if err := ReadCreateMLAnnotationsFromFile(&createMLAnnotations, createMLPath); err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip Dataset
+	if err := DecodeCreateMLAnnotations(&roundTrip, &createMLAnnotations, "testdata"); err != nil {
+		t.Fatal(err)
+	}
+	var got COCOAnnotations
+	if err := EncodeCOCOAnnotations(&got, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Annotations) != len(cocoAnnotations.Annotations) {
+		t.Fatalf("got %v annotations, want %v", len(got.Annotations), len(cocoAnnotations.Annotations))
+	}
+	for index, annotation := range cocoAnnotations.Annotations {
+		if !closeBoxes(got.Annotations[index].BBox, annotation.BBox) {
+			t.Errorf("bbox of annotation %v = %v, want %v", annotation.ID, got.Annotations[index].BBox, annotation.BBox)
+		}
+	}
+	for index, image := range cocoAnnotations.Images {
+		gotImage := got.Images[index]
+		if gotImage.FileName != image.FileName || gotImage.Width != image.Width || gotImage.Height != image.Height {
+			t.Errorf("image %v = %+v, want %+v", index, gotImage, image)
+		}
+	}
+	if gotNames, wantNames := categoryNames(got.Categories), categoryNames(cocoAnnotations.Categories); !reflect.DeepEqual(gotNames, wantNames) {
+		t.Errorf("categories = %v, want %v", gotNames, wantNames)
+	}
+}
+
+func closeBoxes(got []float32, want []float32) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for index := range want {
+		if math.Abs(float64(got[index]-want[index])) > 1e-3 {
+			return false
+		}
+	}
+	return true
+}
+
+func categoryNames(categories []COCOCategory) []string {
+	names := make([]string, len(categories))
+	for index, category := range categories {
+		names[index] = category.Name
+	}
+	return names
+}
@@ -0,0 +1,284 @@
+package model
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// DatasetInfo is the dataset-level description carried between formats
+type DatasetInfo struct {
+	Year        int
+	Version     string
+	Description string
+	Contributor string
+	URL         string
+	DateCreated string
+}
+
+type DatasetLicense struct {
+	ID   int
+	URL  string
+	Name string
+}
+
+type DatasetCategory struct {
+	ID            int
+	Name          string
+	SuperCategory string
+}
+
+type DatasetImage struct {
+	ID           int
+	License      int
+	FileName     string
+	Width        int
+	Height       int
+	Depth        int
+	DateCaptured string
+	Attributes   map[string]string
+
+	// the names of the attributes holding json text rather than a string,
+	// e.g. the number of a coco extra field
+	JSONAttributes map[string]bool
+
+	// the path of the image file when it is not the file name under the
+	// image directory, e.g. an image merged from another dataset
+	Path string
+}
+
+// BoundingBox is an axis-aligned box in absolute pixels, (X, Y) is the top-left corner
+type BoundingBox struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// Polygon is a flat list of x, y pairs in absolute pixels
+type Polygon []float64
+
+type DatasetAnnotation struct {
+	ID           int
+	ImageID      int
+	CategoryID   int
+	BBox         BoundingBox
+	Area         float64
+	Segmentation []Polygon
+	Mask         *RLEMask
+	IsCrowd      bool
+	Attributes   map[string]string
+
+	// the names of the attributes holding json text rather than a string,
+	// e.g. the flags of a voc object
+	JSONAttributes map[string]bool
+}
+
+// Dataset is the format-neutral model, every reader decodes into it and
+// every writer encodes from it
+type Dataset struct {
+	Info        DatasetInfo
+	Licenses    []DatasetLicense
+	Categories  []DatasetCategory
+	Images      []DatasetImage
+	Annotations []DatasetAnnotation
+
+	// format-specific metadata which has no place in the common fields
+	Metadata map[string]string
+
+	// the directory the image file names are relative to
+	ImageDir string
+
+	// the file system ImageDir is in, the os file system if nil
+	ImageFS fs.FS
+}
+
+// AddCategory returns the ID of the category with the name, the category
+// is appended with the next free ID when it does not exist yet
+func (dataset *Dataset) AddCategory(name string) int {
+	maxID := 0
+	for _, category := range dataset.Categories {
+		if category.Name == name {
+			return category.ID
+		}
+		if category.ID > maxID {
+			maxID = category.ID
+		}
+	}
+
+	category := DatasetCategory{
+		ID:   maxID + 1,
+		Name: name,
+	}
+	dataset.Categories = append(dataset.Categories, category)
+	return category.ID
+}
+
+// AddImage appends the image with the next free ID and returns the ID
+func (dataset *Dataset) AddImage(image DatasetImage) int {
+	image.ID = dataset.nextImageID()
+	dataset.Images = append(dataset.Images, image)
+	return image.ID
+}
+
+// AddAnnotation appends the annotation with the next free ID and returns the ID
+func (dataset *Dataset) AddAnnotation(annotation DatasetAnnotation) int {
+	annotation.ID = dataset.nextAnnotationID()
+	dataset.Annotations = append(dataset.Annotations, annotation)
+	return annotation.ID
+}
+
+// appendImage appends the image with the ID after that of the last image and
+// returns the ID. The readers and merge build their datasets from empty with
+// it, so the last ID is the largest and the images are not looked through
+// for every image like AddImage does.
+func (dataset *Dataset) appendImage(image DatasetImage) int {
+	image.ID = 1
+	if length := len(dataset.Images); length > 0 {
+		image.ID = dataset.Images[length-1].ID + 1
+	}
+	dataset.Images = append(dataset.Images, image)
+	return image.ID
+}
+
+// appendAnnotation is like appendImage for the annotations
+func (dataset *Dataset) appendAnnotation(annotation DatasetAnnotation) int {
+	annotation.ID = 1
+	if length := len(dataset.Annotations); length > 0 {
+		annotation.ID = dataset.Annotations[length-1].ID + 1
+	}
+	dataset.Annotations = append(dataset.Annotations, annotation)
+	return annotation.ID
+}
+
+func (dataset *Dataset) nextImageID() int {
+	maxID := 0
+	for _, image := range dataset.Images {
+		maxID = maxInt(maxID, image.ID)
+	}
+	return maxID + 1
+}
+
+func (dataset *Dataset) nextAnnotationID() int {
+	maxID := 0
+	for _, annotation := range dataset.Annotations {
+		maxID = maxInt(maxID, annotation.ID)
+	}
+	return maxID + 1
+}
+
+// CategoryMap returns the categories indexed by ID
+func (dataset *Dataset) CategoryMap() map[int]DatasetCategory {
+	categoryMap := make(map[int]DatasetCategory, len(dataset.Categories))
+	for _, category := range dataset.Categories {
+		categoryMap[category.ID] = category
+	}
+	return categoryMap
+}
+
+// ImageMap returns the images indexed by ID
+func (dataset *Dataset) ImageMap() map[int]DatasetImage {
+	imageMap := make(map[int]DatasetImage, len(dataset.Images))
+	for _, image := range dataset.Images {
+		imageMap[image.ID] = image
+	}
+	return imageMap
+}
+
+// ImagePath returns the path of the image file, the file name is relative to
+// the image directory unless it is absolute or the image has its own path.
+// The path is in ImageFS when it is set.
+func (dataset *Dataset) ImagePath(image *DatasetImage) string {
+	if image.Path != "" {
+		return image.Path
+	}
+	if dataset.ImageFS != nil {
+		return path.Join(dataset.ImageDir, image.FileName)
+	}
+	if filepath.IsAbs(image.FileName) {
+		return image.FileName
+	}
+	return filepath.Join(dataset.ImageDir, filepath.FromSlash(image.FileName))
+}
+
+// StatImage returns the file info of the image file
+func (dataset *Dataset) StatImage(image *DatasetImage) (fs.FileInfo, error) {
+	if dataset.ImageFS != nil {
+		return fs.Stat(dataset.ImageFS, dataset.ImagePath(image))
+	}
+	return os.Stat(dataset.ImagePath(image))
+}
+
+// ReadImageSize reads the width and height of the image from its file
+func (dataset *Dataset) ReadImageSize(image *DatasetImage) (int, int, error) {
+	if dataset.ImageFS != nil {
+		return ReadImageSizeFS(dataset.ImageFS, dataset.ImagePath(image))
+	}
+	return ReadImageSize(dataset.ImagePath(image))
+}
+
+// imageFile is what the file of an image tells, the stat error when it does
+// not exist or its size read from the header
+type imageFile struct {
+	statErr error
+	width   int
+	height  int
+	err     error
+}
+
+// readImageFiles stats the files of the images and reads their sizes with
+// LoadJobs workers, the results keep the order of the images
+func readImageFiles(dataset *Dataset, images []DatasetImage) []imageFile {
+	files := make([]imageFile, len(images))
+	// the failures are kept with the images, none is returned
+	loadParallel(len(images), func(index int) error {
+		file := &files[index]
+		if _, file.statErr = dataset.StatImage(&images[index]); file.statErr == nil {
+			file.width, file.height, file.err = dataset.ReadImageSize(&images[index])
+		}
+		return nil
+	})
+	return files
+}
+
+// AnnotationsByImage groups the annotations by image ID, keeping their order
+func (dataset *Dataset) AnnotationsByImage() map[int][]DatasetAnnotation {
+	annotationMap := make(map[int][]DatasetAnnotation, len(dataset.Images))
+	for _, annotation := range dataset.Annotations {
+		annotationMap[annotation.ImageID] = append(annotationMap[annotation.ImageID], annotation)
+	}
+	return annotationMap
+}
+
+// Subset returns a dataset holding the images with the IDs and their
+// annotations in the original order, the categories and metadata are shared
+// with the dataset so that the IDs stay the same in every subset
+func (dataset *Dataset) Subset(imageIDs []int) *Dataset {
+	imageSet := make(map[int]bool, len(imageIDs))
+	for _, imageID := range imageIDs {
+		imageSet[imageID] = true
+	}
+
+	subset := &Dataset{
+		Info:        dataset.Info,
+		Licenses:    dataset.Licenses,
+		Categories:  dataset.Categories,
+		Images:      make([]DatasetImage, 0, len(imageIDs)),
+		Annotations: make([]DatasetAnnotation, 0),
+		Metadata:    dataset.Metadata,
+		ImageDir:    dataset.ImageDir,
+		ImageFS:     dataset.ImageFS,
+	}
+	for _, image := range dataset.Images {
+		if imageSet[image.ID] {
+			subset.Images = append(subset.Images, image)
+		}
+	}
+	for _, annotation := range dataset.Annotations {
+		if imageSet[annotation.ImageID] {
+			subset.Annotations = append(subset.Annotations, annotation)
+		}
+	}
+	return subset
+}
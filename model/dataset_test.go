@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestAddImageAndAnnotationIDs(t *testing.T) {
+	// the largest IDs are not the last ones
+	dataset := Dataset{
+		Categories:  []DatasetCategory{{ID: 4, Name: "car"}, {ID: 2, Name: "person"}},
+		Images:      []DatasetImage{{ID: 1}, {ID: 7}, {ID: 3}},
+		Annotations: []DatasetAnnotation{{ID: 9, ImageID: 7}, {ID: 2, ImageID: 1}},
+	}
+
+	if id := dataset.AddImage(DatasetImage{FileName: "d.jpg"}); id != 8 {
+		t.Errorf("added image ID = %v, want 8", id)
+	}
+	if id := dataset.AddAnnotation(DatasetAnnotation{ImageID: 8}); id != 10 {
+		t.Errorf("added annotation ID = %v, want 10", id)
+	}
+	if id := dataset.AddCategory("dog"); id != 5 {
+		t.Errorf("added category ID = %v, want 5", id)
+	}
+	if id := dataset.AddCategory("person"); id != 2 {
+		t.Errorf("ID of the existing category = %v, want 2", id)
+	}
+
+	var empty Dataset
+	if imageID, annotationID := empty.AddImage(DatasetImage{}), empty.AddAnnotation(DatasetAnnotation{}); imageID != 1 || annotationID != 1 {
+		t.Errorf("first IDs = %v and %v, want 1", imageID, annotationID)
+	}
+}
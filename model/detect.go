@@ -0,0 +1,178 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// DetectFormat finds the registered format of the dataset at the path, it
+// fails if no format or more than one format claims the dataset
+func DetectFormat(path string) (*Format, error) {
+	var matches []*Format
+	for _, format := range Formats() {
+		if format.Detect != nil && format.Detect(path) {
+			matches = append(matches, format)
+		}
+	}
+
+	return detectedFormat(matches, path)
+}
+
+// DetectFormatFS is like DetectFormat but finds the format of the dataset at
+// the path of the file system among the formats which can detect it there
+func DetectFormatFS(fsys fs.FS, name string) (*Format, error) {
+	var matches []*Format
+	for _, format := range Formats() {
+		if format.DetectFS != nil && format.DetectFS(fsys, name) {
+			matches = append(matches, format)
+		}
+	}
+	return detectedFormat(matches, fsPath(fsys, name))
+}
+
+// detectedFormat returns the only format claiming the dataset at the path
+func detectedFormat(matches []*Format, path string) (*Format, error) {
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("can not detect the format of %v, please specify it", path)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for index, format := range matches {
+			names[index] = format.Name
+		}
+		return nil, fmt.Errorf("the format of %v is ambiguous (%v), please specify it", path, strings.Join(names, ", "))
+	}
+}
+
+// sniffJSONKeys returns the kind of the top-level json value of the file,
+// '{' or '[', and the keys of the object, or of the first object in the
+// array. Only the keys listed in wanted are collected, the scan stops as
+// soon as all of them are found
+func sniffJSONKeys(fsys fs.FS, name string, wanted ...string) (json.Delim, map[string]bool, error) {
+	if strings.ToLower(path.Ext(name)) != ".json" {
+		return 0, nil, fmt.Errorf("%v is not a json file", fsPath(fsys, name))
+	}
+
+	jsonFile, err := fsys.Open(name)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer jsonFile.Close()
+
+	decoder := json.NewDecoder(jsonFile)
+	token, err := decoder.Token()
+	if err != nil {
+		return 0, nil, err
+	}
+	kind, ok := token.(json.Delim)
+	if !ok || (kind != '{' && kind != '[') {
+		return 0, nil, fmt.Errorf("%v does not hold a json object or array", fsPath(fsys, name))
+	}
+
+	keys := make(map[string]bool)
+	if kind == '[' {
+		if !decoder.More() {
+			return kind, keys, nil
+		}
+		if token, err = decoder.Token(); err != nil {
+			return 0, nil, err
+		}
+		if token != json.Delim('{') {
+			return kind, keys, nil
+		}
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, nil, err
+		}
+		key, _ := token.(string)
+		for _, name := range wanted {
+			if key == name {
+				keys[key] = true
+			}
+		}
+		if len(keys) == len(wanted) {
+			break
+		}
+		if err := skipJSONValue(decoder); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return kind, keys, nil
+}
+
+// skipJSONValue consumes the next value of the decoder without keeping it
+func skipJSONValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// detectCOCOFile reports whether the file is a json object with images and
+// annotations or categories
+func detectCOCOFile(fsys fs.FS, name string) bool {
+	kind, keys, err := sniffJSONKeys(fsys, name, "images", "annotations", "categories")
+	return err == nil && kind == '{' && keys["images"] && (keys["annotations"] || keys["categories"])
+}
+
+// detectCreateMLFile reports whether the file is a json array of objects
+// with image and annotations
+func detectCreateMLFile(fsys fs.FS, name string) bool {
+	kind, keys, err := sniffJSONKeys(fsys, name, "image", "annotations")
+	return err == nil && kind == '[' && keys["image"]
+}
+
+// detectPascalVOCPath reports whether the os path is a voc dataset
+func detectPascalVOCPath(path string) bool {
+	fsys, name := vocPathFS(path)
+	return detectPascalVOCDir(fsys, name)
+}
+
+// detectPascalVOCDir reports whether the name is a voc dataset, a directory
+// holding voc xml annotation files directly or in Annotations/ of the
+// VOC2012 like directory or the VOCdevkit, or an image set list of it
+func detectPascalVOCDir(fsys fs.FS, name string) bool {
+	dataset, err := resolveVOCDataset(fsys, name)
+	if err != nil {
+		// a VOCdevkit holding several datasets is still voc
+		return isDir(fsys, name) && len(vocDevkitYears(fsys, name)) > 1
+	}
+	if dataset.imageSet != "" {
+		return true
+	}
+
+	entries, err := fs.ReadDir(fsys, dataset.annotationDir)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.ToLower(path.Ext(entry.Name())) != ".xml" {
+			continue
+		}
+		var annotation VOCAnnotation
+		return readVOCAnnotationFS(&annotation, fsys, path.Join(dataset.annotationDir, entry.Name())) == nil
+	}
+	return false
+}
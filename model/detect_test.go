@@ -0,0 +1,120 @@
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const detectTestXML = `<annotation>
+    <filename>a.jpg</filename>
+    <size><width>100</width><height>80</height><depth>3</depth></size>
+    <object>
+        <name>car</name>
+        <bndbox><xmin>10</xmin><ymin>20</ymin><xmax>40</xmax><ymax>60</ymax></bndbox>
+    </object>
+</annotation>
+`
+
+const detectTestLabel = "0 0.25 0.5 0.3 0.5\n"
+
+// detectTestFiles is a dataset of every format by its directory or file
+var detectTestFiles = map[string]string{
+	"coco.json":       `{"info": {}, "images": [], "categories": []}`,
+	"createml.json":   `[{"image": "a.jpg", "annotations": []}]`,
+	"other.json":      `{"images": []}`,
+	"voc/a.xml":       detectTestXML,
+	"empty/readme.md": "nothing\n",
+
+	// the annotations of the vocdevkit layout
+	"voc2012/Annotations/a.xml": detectTestXML,
+
+	"yolo/classes.txt":  "car\n",
+	"yolo/images/a.jpg": "",
+	"yolo/labels/a.txt": detectTestLabel,
+
+	"darknet/classes.txt": "car\n",
+	"darknet/a.txt":       detectTestLabel,
+
+	// the voc annotations beside the yolo class names and labels
+	"both/a.xml":       detectTestXML,
+	"both/a.txt":       detectTestLabel,
+	"both/classes.txt": "car\n",
+}
+
+// writeTestFiles writes the files by their slash separated paths under the
+// directory
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// detectTests are the formats detected of the detectTestFiles, or the errors
+var detectTests = []struct {
+	name   string
+	format string
+	err    string
+}{
+	{"coco.json", "coco", ""},
+	{"createml.json", "createml", ""},
+	{"voc", "voc", ""},
+	{"voc2012", "voc", ""},
+	{"yolo", "yolo", ""},
+	{"darknet", "yolo", ""},
+	{"other.json", "", "can not detect the format of"},
+	{"empty", "", "can not detect the format of"},
+	{"missing.json", "", "can not detect the format of"},
+	{"both", "", "is ambiguous (voc, yolo)"},
+}
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, detectTestFiles)
+
+	for _, test := range detectTests {
+		format, err := DetectFormat(filepath.Join(dir, test.name))
+		checkDetectedFormat(t, test.name, format, err, test.format, test.err)
+	}
+}
+
+func TestDetectFormatFS(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for name, content := range detectTestFiles {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	for _, test := range detectTests {
+		format, err := DetectFormatFS(fsys, test.name)
+		checkDetectedFormat(t, test.name, format, err, test.format, test.err)
+	}
+}
+
+// checkDetectedFormat checks the format detected of the name is the wanted
+// one, or the error contains the wanted error
+func checkDetectedFormat(t *testing.T, name string, format *Format, err error, wantFormat string, wantErr string) {
+	t.Helper()
+
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("error detecting %v = %v, want %v", name, err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("detecting %v: %v", name, err)
+	} else if format.Name != wantFormat {
+		t.Errorf("detected %v for %v, want %v", format.Name, name, wantFormat)
+	}
+}
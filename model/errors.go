@@ -0,0 +1,61 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// MissingImageError is returned when the file of an image does not exist
+type MissingImageError struct {
+	// the path of the image file
+	Path string
+	Err  error
+}
+
+func (e *MissingImageError) Error() string {
+	return fmt.Sprintf("image [%v] opening... %v", e.Path, e.Err.Error())
+}
+
+func (e *MissingImageError) Unwrap() error {
+	return e.Err
+}
+
+// imageOpenError returns the error of opening the image file at the path, a
+// MissingImageError when the file does not exist
+func imageOpenError(path string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &MissingImageError{Path: path, Err: err}
+	}
+	return fmt.Errorf("image [%v] opening... %w", path, err)
+}
+
+// the kinds of the records an annotation refers to
+const (
+	ReferenceImage    = "image"
+	ReferenceCategory = "category"
+)
+
+// DanglingReferenceError is returned when an annotation refers to an image or
+// category which does not exist in the dataset
+type DanglingReferenceError struct {
+	// ReferenceImage or ReferenceCategory
+	Kind string
+
+	// the ID referred to
+	ID int
+
+	// the ID of the annotation
+	AnnotationID int
+
+	// the name of the dataset the annotation belongs to, empty if there is
+	// only one
+	Dataset string
+}
+
+func (e *DanglingReferenceError) Error() string {
+	if e.Dataset != "" {
+		return fmt.Sprintf("the %v with ID[%v] does not exist(annotation with ID[%v] of %v)", e.Kind, e.ID, e.AnnotationID, e.Dataset)
+	}
+	return fmt.Sprintf("the %v with ID[%v] does not exist(annotation with ID[%v])", e.Kind, e.ID, e.AnnotationID)
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMissingImageError(t *testing.T) {
+	// the annotations of the createml file refer to a.jpg and b.jpg
+	fsys := fstest.MapFS{
+		"annotations.json": &fstest.MapFile{Data: []byte(`[
+			{"image": "a.jpg", "annotations": []},
+			{"image": "b.jpg", "annotations": []}
+		]`)},
+	}
+
+	var dataset Dataset
+	err := ReadDatasetFromCreateMLFS(&dataset, fsys, "annotations.json")
+	var missing *MissingImageError
+	if !errors.As(err, &missing) {
+		t.Fatalf("error = %v, want a MissingImageError", err)
+	}
+	if missing.Path != "a.jpg" || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing image = %v, want a.jpg not existing", missing.Path)
+	}
+}
+
+func TestDanglingReferenceError(t *testing.T) {
+	dataset := Dataset{
+		Images:      []DatasetImage{{ID: 1, FileName: "a.jpg", Width: 10, Height: 10}},
+		Categories:  []DatasetCategory{{ID: 1, Name: "car"}},
+		Annotations: []DatasetAnnotation{{ID: 7, ImageID: 1, CategoryID: 2}},
+	}
+
+	var annotations CreateMLAnnotations
+	err := EncodeCreateMLAnnotations(&annotations, &dataset)
+	var dangling *DanglingReferenceError
+	if !errors.As(err, &dangling) {
+		t.Fatalf("error = %v, want a DanglingReferenceError", err)
+	}
+	if dangling.Kind != ReferenceCategory || dangling.ID != 2 || dangling.AnnotationID != 7 {
+		t.Errorf("dangling reference = %+v, want category 2 of annotation 7", *dangling)
+	}
+}
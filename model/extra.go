@@ -0,0 +1,98 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// jsonExtraFields returns the fields of the json object which are not known
+func jsonExtraFields(data []byte, known map[string]bool) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name := range fields {
+		if known[name] {
+			delete(fields, name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// appendJSONExtraFields adds the extra fields in name order to the end of the
+// marshaled json object
+func appendJSONExtraFields(data []byte, extra map[string]json.RawMessage) ([]byte, error) {
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buffer := bytes.NewBuffer(bytes.TrimSuffix(data, []byte("}")))
+	for _, name := range names {
+		nameBytes, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteByte(',')
+		buffer.Write(nameBytes)
+		buffer.WriteByte(':')
+		buffer.Write(extra[name])
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// extraToAttributes keeps the strings as they are and the other json
+// values as their json text, the names of which are returned as well
+func extraToAttributes(extra map[string]json.RawMessage) (map[string]string, map[string]bool) {
+	if len(extra) == 0 {
+		return nil, nil
+	}
+
+	attributes := make(map[string]string, len(extra))
+	var jsonAttributes map[string]bool
+	for name, value := range extra {
+		var text string
+		if err := json.Unmarshal(value, &text); err == nil {
+			attributes[name] = text
+			continue
+		}
+		attributes[name] = string(value)
+		if jsonAttributes == nil {
+			jsonAttributes = make(map[string]bool)
+		}
+		jsonAttributes[name] = true
+	}
+	return attributes, jsonAttributes
+}
+
+// attributesToExtra is the reverse of extraToAttributes, the json attributes
+// are written as they are and all the others as strings, even those looking
+// like numbers
+func attributesToExtra(attributes map[string]string, jsonAttributes map[string]bool) map[string]json.RawMessage {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	extra := make(map[string]json.RawMessage, len(attributes))
+	for name, value := range attributes {
+		if trimmed := strings.TrimSpace(value); jsonAttributes[name] && json.Valid([]byte(trimmed)) {
+			extra[name] = json.RawMessage(trimmed)
+			continue
+		}
+		// marshaling a string never fails
+		extra[name], _ = json.Marshal(value)
+	}
+	return extra
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAttributesKeepTheirJSONTypes(t *testing.T) {
+	// the strings look like json but must stay strings
+	const data = `{
+		"images": [{"id": 1, "file_name": "a.jpg", "width": 10, "height": 10, "video_id": 5, "tags": ["day"]}],
+		"annotations": [{"id": 1, "image_id": 1, "category_id": 1, "bbox": [1, 2, 3, 4],
+			"count": "123", "checked": "true", "note": "null", "score": 0.5}],
+		"categories": [{"id": 1, "name": "car"}]
+	}`
+
+	var dataset Dataset
+	if err := DecodeCOCOStream(&dataset, strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	wantAttributes := map[string]string{"count": "123", "checked": "true", "note": "null", "score": "0.5"}
+	if got := dataset.Annotations[0].Attributes; !reflect.DeepEqual(got, wantAttributes) {
+		t.Errorf("attributes = %v, want %v", got, wantAttributes)
+	}
+
+	// coco to createml to coco
+	var createML bytes.Buffer
+	if err := EncodeCreateMLStream(&createML, &dataset); err != nil {
+		t.Fatal(err)
+	}
+	image, err := ioutil.ReadFile(filepath.Join("testdata", "voc", "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"a.json": &fstest.MapFile{Data: createML.Bytes()},
+		"a.jpg":  &fstest.MapFile{Data: image},
+	}
+	var roundTrip Dataset
+	if err := ReadDatasetFromCreateMLFS(&roundTrip, fsys, "a.json"); err != nil {
+		t.Fatal(err)
+	}
+	if got := roundTrip.Annotations[0].Attributes; !reflect.DeepEqual(got, wantAttributes) {
+		t.Errorf("attributes read from createml = %v, want %v", got, wantAttributes)
+	}
+	// the images of createml have no attributes
+	roundTrip.Images[0] = dataset.Images[0]
+
+	var written bytes.Buffer
+	if err := EncodeCOCOStream(&written, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	var fields struct {
+		Images      []map[string]json.RawMessage `json:"images"`
+		Annotations []map[string]json.RawMessage `json:"annotations"`
+	}
+	if err := json.Unmarshal(written.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"count": `"123"`, "checked": `"true"`, "note": `"null"`, "score": `0.5`}
+	for name, value := range want {
+		if got := string(fields.Annotations[0][name]); got != value {
+			t.Errorf("written %v = %v, want %v", name, got, value)
+		}
+	}
+	for name, value := range map[string]string{"video_id": `5`, "tags": `["day"]`} {
+		var got bytes.Buffer
+		if err := json.Compact(&got, fields.Images[0][name]); err != nil || got.String() != value {
+			t.Errorf("written %v = %v, want %v", name, got.String(), value)
+		}
+	}
+}
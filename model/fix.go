@@ -0,0 +1,271 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// the actions of the fix
+const (
+	FixRenumberID       = "renumber-id"
+	FixCorrectSize      = "correct-size"
+	FixRemoveImage      = "remove-image"
+	FixRemoveAnnotation = "remove-annotation"
+	FixSwapBox          = "swap-box"
+	FixClipBox          = "clip-box"
+)
+
+// FixChange is a modification made by the fix
+type FixChange struct {
+	Action  string `json:"action" yaml:"action"`
+	Message string `json:"message" yaml:"message"`
+
+	// the image, annotation or category the change is about, if any
+	Image        string `json:"image,omitempty" yaml:"image,omitempty"`
+	ImageID      int    `json:"image_id,omitempty" yaml:"image_id,omitempty"`
+	AnnotationID int    `json:"annotation_id,omitempty" yaml:"annotation_id,omitempty"`
+	CategoryID   int    `json:"category_id,omitempty" yaml:"category_id,omitempty"`
+}
+
+// FixChangelog is the modifications of a dataset in the order they were made
+type FixChangelog struct {
+	Format  string      `json:"format" yaml:"format"`
+	Path    string      `json:"path" yaml:"path"`
+	Output  string      `json:"output" yaml:"output"`
+	Changes []FixChange `json:"changes" yaml:"changes"`
+}
+
+type FixOptions struct {
+	// correct the image sizes from the image headers and remove the images
+	// whose files are missing
+	CheckImages bool
+
+	// the boxes narrower or shorter than it after clipping are removed
+	MinBoxSize float64
+}
+
+func (changelog *FixChangelog) add(change FixChange) {
+	changelog.Changes = append(changelog.Changes, change)
+}
+
+// FixDataset repairs the defects found by ValidateDataset in place: the
+// duplicate IDs are renumbered, the image sizes corrected, the inverted boxes
+// swapped and the boxes clipped to the images, and the annotations of missing
+// images and categories or with degenerate boxes removed
+func FixDataset(dataset *Dataset, options FixOptions) FixChangelog {
+	changelog := FixChangelog{Changes: make([]FixChange, 0)}
+
+	fixCategoryIDs(&changelog, dataset)
+	fixImages(&changelog, dataset, options)
+	fixAnnotations(&changelog, dataset, options)
+
+	return changelog
+}
+
+// fixCategoryIDs renumbers the categories whose IDs are used before, the
+// annotations keep referring to the first one
+func fixCategoryIDs(changelog *FixChangelog, dataset *Dataset) {
+	maxID := 0
+	for _, category := range dataset.Categories {
+		maxID = maxInt(maxID, category.ID)
+	}
+
+	seen := make(map[int]bool, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		if seen[category.ID] {
+			maxID++
+			dataset.Categories[index].ID = maxID
+			changelog.add(FixChange{
+				Action:     FixRenumberID,
+				Message:    fmt.Sprintf("the duplicate ID[%v] of category [%v] is renumbered to ID[%v]", category.ID, category.Name, maxID),
+				CategoryID: maxID,
+			})
+		}
+		seen[dataset.Categories[index].ID] = true
+	}
+}
+
+// fixImages renumbers the duplicate image IDs, the annotations keep referring
+// to the first image, and corrects the sizes from the image files
+func fixImages(changelog *FixChangelog, dataset *Dataset, options FixOptions) {
+	maxID := 0
+	for _, image := range dataset.Images {
+		maxID = maxInt(maxID, image.ID)
+	}
+
+	var files []imageFile
+	if options.CheckImages {
+		files = readImageFiles(dataset, dataset.Images)
+	}
+
+	seen := make(map[int]bool, len(dataset.Images))
+	images := make([]DatasetImage, 0, len(dataset.Images))
+	for index, image := range dataset.Images {
+		if seen[image.ID] {
+			maxID++
+			changelog.add(FixChange{
+				Action:  FixRenumberID,
+				Message: fmt.Sprintf("the duplicate ID[%v] of image [%v] is renumbered to ID[%v]", image.ID, image.FileName, maxID),
+				Image:   image.FileName,
+				ImageID: maxID,
+			})
+			image.ID = maxID
+		}
+		seen[image.ID] = true
+
+		if options.CheckImages {
+			file := files[index]
+			if file.statErr != nil {
+				changelog.add(FixChange{
+					Action:  FixRemoveImage,
+					Message: fmt.Sprintf("the image [%v] is removed as its file [%v] does not exist", image.FileName, dataset.ImagePath(&image)),
+					Image:   image.FileName,
+					ImageID: image.ID,
+				})
+				continue
+			}
+
+			if file.err == nil && (file.width != image.Width || file.height != image.Height) {
+				changelog.add(FixChange{
+					Action:  FixCorrectSize,
+					Message: fmt.Sprintf("the size %vx%v of image [%v] is corrected to %vx%v from its file", image.Width, image.Height, image.FileName, file.width, file.height),
+					Image:   image.FileName,
+					ImageID: image.ID,
+				})
+				image.Width, image.Height = file.width, file.height
+			}
+		}
+
+		images = append(images, image)
+	}
+	dataset.Images = images
+}
+
+// fixAnnotations removes the annotations which can not be repaired, fixes the
+// boxes of the others and renumbers the duplicate annotation IDs
+func fixAnnotations(changelog *FixChangelog, dataset *Dataset, options FixOptions) {
+	imageMap := dataset.ImageMap()
+	categoryMap := dataset.CategoryMap()
+
+	maxID := 0
+	for _, annotation := range dataset.Annotations {
+		maxID = maxInt(maxID, annotation.ID)
+	}
+
+	seen := make(map[int]bool, len(dataset.Annotations))
+	annotations := make([]DatasetAnnotation, 0, len(dataset.Annotations))
+	for _, annotation := range dataset.Annotations {
+		image, ok := imageMap[annotation.ImageID]
+		change := FixChange{
+			Action:       FixRemoveAnnotation,
+			Image:        image.FileName,
+			ImageID:      annotation.ImageID,
+			AnnotationID: annotation.ID,
+		}
+
+		if !ok {
+			change.Message = fmt.Sprintf("the annotation with ID[%v] is removed as its image with ID[%v] does not exist", annotation.ID, annotation.ImageID)
+			changelog.add(change)
+			continue
+		}
+		if _, ok := categoryMap[annotation.CategoryID]; !ok {
+			change.Message = fmt.Sprintf("the annotation with ID[%v] is removed as its category with ID[%v] does not exist", annotation.ID, annotation.CategoryID)
+			change.CategoryID = annotation.CategoryID
+			changelog.add(change)
+			continue
+		}
+		if !fixBox(changelog, &annotation, image, options.MinBoxSize) {
+			change.Message = fmt.Sprintf("the annotation with ID[%v] is removed as its box %v is degenerate", annotation.ID, formatBox(annotation.BBox))
+			changelog.add(change)
+			continue
+		}
+
+		if seen[annotation.ID] {
+			maxID++
+			changelog.add(FixChange{
+				Action:       FixRenumberID,
+				Message:      fmt.Sprintf("the duplicate ID[%v] of annotation is renumbered to ID[%v]", annotation.ID, maxID),
+				Image:        image.FileName,
+				ImageID:      annotation.ImageID,
+				AnnotationID: maxID,
+			})
+			annotation.ID = maxID
+		}
+		seen[annotation.ID] = true
+
+		annotations = append(annotations, annotation)
+	}
+	dataset.Annotations = annotations
+}
+
+// fixBox swaps the inverted sides of the box and clips it to the image,
+// returns false when the box is degenerate and has to be removed
+func fixBox(changelog *FixChangelog, annotation *DatasetAnnotation, image DatasetImage, minSize float64) bool {
+	box := annotation.BBox
+	for _, value := range []float64{box.X, box.Y, box.Width, box.Height} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return false
+		}
+	}
+
+	change := FixChange{
+		Image:        image.FileName,
+		ImageID:      image.ID,
+		AnnotationID: annotation.ID,
+	}
+
+	if box.Width < 0 || box.Height < 0 {
+		if box.Width < 0 {
+			box.X, box.Width = box.X+box.Width, -box.Width
+		}
+		if box.Height < 0 {
+			box.Y, box.Height = box.Y+box.Height, -box.Height
+		}
+		change.Action = FixSwapBox
+		change.Message = fmt.Sprintf("the inverted box %v of annotation with ID[%v] is swapped to %v", formatBox(annotation.BBox), annotation.ID, formatBox(box))
+		changelog.add(change)
+	}
+
+	if image.Width > 0 && image.Height > 0 {
+		xmin := math.Max(box.X, 0)
+		ymin := math.Max(box.Y, 0)
+		xmax := math.Min(box.X+box.Width, float64(image.Width))
+		ymax := math.Min(box.Y+box.Height, float64(image.Height))
+		clipped := BoundingBox{
+			X:      xmin,
+			Y:      ymin,
+			Width:  math.Max(xmax-xmin, 0),
+			Height: math.Max(ymax-ymin, 0),
+		}
+		if clipped != box {
+			change.Action = FixClipBox
+			change.Message = fmt.Sprintf("the box %v of annotation with ID[%v] is clipped to %v in the image of %vx%v", formatBox(box), annotation.ID, formatBox(clipped), image.Width, image.Height)
+			changelog.add(change)
+			box = clipped
+		}
+	}
+
+	if box.Width <= 0 || box.Height <= 0 || box.Width < minSize || box.Height < minSize {
+		return false
+	}
+
+	if box != annotation.BBox {
+		// the area of a box-only annotation follows the box
+		if annotation.Mask == nil && len(annotation.Segmentation) == 0 {
+			annotation.Area = box.Width * box.Height
+		}
+		annotation.BBox = box
+	}
+	return true
+}
+
+func WriteFixChangelogToFile(changelog *FixChangelog, path string) error {
+	changelogBytes, err := json.MarshalIndent(changelog, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, changelogBytes, 0666)
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFixDataset(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, dataset *Dataset)
+		options FixOptions
+		actions []string
+		check   func(t *testing.T, dataset *Dataset)
+	}{
+		{
+			name:   "valid",
+			modify: func(t *testing.T, dataset *Dataset) {},
+		},
+		{
+			name: "duplicate category ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Categories = append(dataset.Categories, DatasetCategory{ID: 1, Name: "truck"})
+			},
+			actions: []string{FixRenumberID},
+			check: func(t *testing.T, dataset *Dataset) {
+				if dataset.Categories[1].ID != 2 || dataset.Annotations[0].CategoryID != 1 {
+					t.Errorf("truck has ID[%v] and the car annotation refers to ID[%v], want 2 and 1", dataset.Categories[1].ID, dataset.Annotations[0].CategoryID)
+				}
+			},
+		},
+		{
+			name: "duplicate image ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images = append(dataset.Images, DatasetImage{ID: 1, FileName: "b.jpg", Width: 110, Height: 85})
+			},
+			actions: []string{FixRenumberID},
+			check: func(t *testing.T, dataset *Dataset) {
+				if dataset.Images[1].ID != 2 || dataset.Annotations[0].ImageID != 1 {
+					t.Errorf("b.jpg has ID[%v] and the annotation refers to ID[%v], want 2 and 1", dataset.Images[1].ID, dataset.Annotations[0].ImageID)
+				}
+			},
+		},
+		{
+			name: "duplicate annotation ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations = append(dataset.Annotations, dataset.Annotations[0])
+			},
+			actions: []string{FixRenumberID},
+			check: func(t *testing.T, dataset *Dataset) {
+				if dataset.Annotations[0].ID != 1 || dataset.Annotations[1].ID != 2 {
+					t.Errorf("the annotation IDs are %v and %v, want 1 and 2", dataset.Annotations[0].ID, dataset.Annotations[1].ID)
+				}
+			},
+		},
+		{
+			name: "dangling image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].ImageID = 9
+			},
+			actions: []string{FixRemoveAnnotation},
+		},
+		{
+			name: "dangling category",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].CategoryID = 9
+			},
+			actions: []string{FixRemoveAnnotation},
+		},
+		{
+			name: "invalid number",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.Width = math.Inf(1)
+			},
+			actions: []string{FixRemoveAnnotation},
+		},
+		{
+			name: "zero area",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.Width = 0
+			},
+			actions: []string{FixRemoveAnnotation},
+		},
+		{
+			name: "inverted box",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: 30, Y: 30, Width: -20, Height: -20}
+			},
+			actions: []string{FixSwapBox},
+			check:   checkFixedBox(BoundingBox{X: 10, Y: 10, Width: 20, Height: 20}, 400),
+		},
+		{
+			name: "box exceeding the image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: -5, Y: 70, Width: 20, Height: 20}
+			},
+			actions: []string{FixClipBox},
+			check:   checkFixedBox(BoundingBox{X: 0, Y: 70, Width: 15, Height: 10}, 150),
+		},
+		{
+			name: "clipped segmentation",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: 90, Y: 70, Width: 20, Height: 20}
+				dataset.Annotations[0].Segmentation = []Polygon{{90, 70, 110, 70, 110, 90}}
+				dataset.Annotations[0].Area = 200
+			},
+			actions: []string{FixClipBox},
+			// the area of the segmentation is kept
+			check: checkFixedBox(BoundingBox{X: 90, Y: 70, Width: 10, Height: 10}, 200),
+		},
+		{
+			name: "box outside the image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: 120, Y: 10, Width: 10, Height: 10}
+			},
+			actions: []string{FixClipBox, FixRemoveAnnotation},
+		},
+		{
+			name: "box under the min size",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.Width = 4
+			},
+			options: FixOptions{MinBoxSize: 5},
+			actions: []string{FixRemoveAnnotation},
+		},
+		{
+			name: "wrong size",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images[0].Width, dataset.Images[0].Height = 50, 50
+			},
+			options: FixOptions{CheckImages: true},
+			actions: []string{FixCorrectSize},
+			check: func(t *testing.T, dataset *Dataset) {
+				if dataset.Images[0].Width != 100 || dataset.Images[0].Height != 80 {
+					t.Errorf("size = %vx%v, want 100x80", dataset.Images[0].Width, dataset.Images[0].Height)
+				}
+			},
+		},
+		{
+			name: "missing image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.ImageDir = t.TempDir()
+			},
+			options: FixOptions{CheckImages: true},
+			actions: []string{FixRemoveImage, FixRemoveAnnotation},
+			check: func(t *testing.T, dataset *Dataset) {
+				if len(dataset.Images) != 0 {
+					t.Errorf("images = %+v, want none", dataset.Images)
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataset := newValidateTestDataset()
+			test.modify(t, dataset)
+			changelog := FixDataset(dataset, test.options)
+
+			actions := make([]string, 0)
+			for _, change := range changelog.Changes {
+				actions = append(actions, change.Action)
+			}
+			if test.actions == nil {
+				test.actions = []string{}
+			}
+			if !reflect.DeepEqual(actions, test.actions) {
+				t.Errorf("changes = %+v, want %v", changelog.Changes, test.actions)
+			}
+			if test.check != nil {
+				test.check(t, dataset)
+			}
+
+			// the fixed dataset has nothing left to fix
+			if report := ValidateDataset(dataset, ValidationOptions{CheckImages: test.options.CheckImages}); report.Errors > 0 || report.Warnings > 0 {
+				t.Errorf("issues after the fix = %+v", report.Issues)
+			}
+		})
+	}
+}
+
+func checkFixedBox(box BoundingBox, area float64) func(t *testing.T, dataset *Dataset) {
+	return func(t *testing.T, dataset *Dataset) {
+		if len(dataset.Annotations) != 1 {
+			t.Fatalf("annotations = %+v, want one", dataset.Annotations)
+		}
+		if annotation := dataset.Annotations[0]; annotation.BBox != box || annotation.Area != area {
+			t.Errorf("box = %+v of area %v, want %+v of area %v", annotation.BBox, annotation.Area, box, area)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DatasetReader reads the dataset stored at the path, a file or directory
+type DatasetReader interface {
+	ReadDataset(dataset *Dataset, path string) error
+}
+
+// DatasetWriter writes the dataset to the path, a file or directory
+type DatasetWriter interface {
+	WriteDataset(dataset *Dataset, path string) error
+}
+
+// DatasetReaderFunc adapts a function to the DatasetReader interface
+type DatasetReaderFunc func(dataset *Dataset, path string) error
+
+func (f DatasetReaderFunc) ReadDataset(dataset *Dataset, path string) error {
+	return f(dataset, path)
+}
+
+// DatasetWriterFunc adapts a function to the DatasetWriter interface
+type DatasetWriterFunc func(dataset *Dataset, path string) error
+
+func (f DatasetWriterFunc) WriteDataset(dataset *Dataset, path string) error {
+	return f(dataset, path)
+}
+
+// DatasetFSReader reads the dataset stored at the path of the file system
+type DatasetFSReader interface {
+	ReadDatasetFS(dataset *Dataset, fsys fs.FS, path string) error
+}
+
+// DatasetDecoder decodes the dataset of a single-file format from r
+type DatasetDecoder interface {
+	DecodeDataset(dataset *Dataset, r io.Reader) error
+}
+
+// DatasetEncoder encodes the dataset of a single-file format to w
+type DatasetEncoder interface {
+	EncodeDataset(w io.Writer, dataset *Dataset) error
+}
+
+// DatasetRecords receives the records of a dataset one at a time as they are
+// decoded, a nil function skips the records of its kind
+type DatasetRecords struct {
+	Info       func(info DatasetInfo) error
+	License    func(license DatasetLicense) error
+	Category   func(category DatasetCategory) error
+	Image      func(image DatasetImage) error
+	Annotation func(annotation DatasetAnnotation) error
+}
+
+// DatasetRecordDecoder decodes the records of a single-file format from r and
+// passes them on without keeping them, so the memory needed does not grow
+// with the dataset
+type DatasetRecordDecoder interface {
+	DecodeRecords(r io.Reader, records DatasetRecords) error
+}
+
+// DatasetFSReaderFunc adapts a function to the DatasetFSReader interface
+type DatasetFSReaderFunc func(dataset *Dataset, fsys fs.FS, path string) error
+
+func (f DatasetFSReaderFunc) ReadDatasetFS(dataset *Dataset, fsys fs.FS, path string) error {
+	return f(dataset, fsys, path)
+}
+
+// DatasetDecoderFunc adapts a function to the DatasetDecoder interface
+type DatasetDecoderFunc func(dataset *Dataset, r io.Reader) error
+
+func (f DatasetDecoderFunc) DecodeDataset(dataset *Dataset, r io.Reader) error {
+	return f(dataset, r)
+}
+
+// DatasetEncoderFunc adapts a function to the DatasetEncoder interface
+type DatasetEncoderFunc func(w io.Writer, dataset *Dataset) error
+
+func (f DatasetEncoderFunc) EncodeDataset(w io.Writer, dataset *Dataset) error {
+	return f(w, dataset)
+}
+
+// DatasetRecordDecoderFunc adapts a function to the DatasetRecordDecoder interface
+type DatasetRecordDecoderFunc func(r io.Reader, records DatasetRecords) error
+
+func (f DatasetRecordDecoderFunc) DecodeRecords(r io.Reader, records DatasetRecords) error {
+	return f(r, records)
+}
+
+// Format describes a dataset format which can be read and written
+type Format struct {
+	// the unique name of the format, e.g. coco
+	Name string
+
+	// other names accepted for the format
+	Aliases []string
+
+	// a short human readable description
+	Description string
+
+	// the file extension of the dataset, empty if the dataset is a directory
+	Extension string
+
+	// Detect reports whether the path holds a dataset of the format
+	Detect func(path string) bool
+
+	Reader DatasetReader
+	Writer DatasetWriter
+
+	// DetectFS and FSReader are like Detect and Reader on a file system
+	// other than the os one, they are optional
+	DetectFS func(fsys fs.FS, path string) bool
+	FSReader DatasetFSReader
+
+	// the stream codec of a single-file format, both are optional
+	Decoder DatasetDecoder
+	Encoder DatasetEncoder
+
+	// RecordDecoder decodes the stream of a single-file format record by
+	// record, it is optional
+	RecordDecoder DatasetRecordDecoder
+}
+
+var (
+	formatsMutex sync.RWMutex
+	formats      = make(map[string]*Format)
+	formatNames  = make(map[string]string)
+)
+
+// RegisterFormat adds the format to the registry, the name and aliases of
+// the format must not be used by any registered format
+func RegisterFormat(format *Format) error {
+	if format.Name == "" {
+		return fmt.Errorf("the format name must not be empty")
+	}
+	if format.Reader == nil && format.Writer == nil && format.FSReader == nil && format.Decoder == nil && format.Encoder == nil {
+		return fmt.Errorf("the format %v has neither reader nor writer", format.Name)
+	}
+
+	formatsMutex.Lock()
+	defer formatsMutex.Unlock()
+
+	names := append([]string{format.Name}, format.Aliases...)
+	for _, name := range names {
+		if registered, ok := formatNames[strings.ToLower(name)]; ok {
+			return fmt.Errorf("the format name %v is already used by %v", name, registered)
+		}
+	}
+
+	formats[format.Name] = format
+	for _, name := range names {
+		formatNames[strings.ToLower(name)] = format.Name
+	}
+	return nil
+}
+
+// MustRegisterFormat is like RegisterFormat but panics if the format can not be registered
+func MustRegisterFormat(format *Format) {
+	if err := RegisterFormat(format); err != nil {
+		panic(err)
+	}
+}
+
+// LookupFormat returns the registered format by its name or one of its aliases
+func LookupFormat(name string) (*Format, error) {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+
+	if formatName, ok := formatNames[strings.ToLower(name)]; ok {
+		return formats[formatName], nil
+	}
+
+	return nil, fmt.Errorf("the format %q is not supported, valid formats: %v", name, strings.Join(formatNameList(), ", "))
+}
+
+// Formats returns all the registered formats sorted by name
+func Formats() []*Format {
+	formatsMutex.RLock()
+	defer formatsMutex.RUnlock()
+
+	list := make([]*Format, 0, len(formats))
+	for _, name := range formatNameList() {
+		list = append(list, formats[name])
+	}
+	return list
+}
+
+func formatNameList() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
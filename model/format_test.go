@@ -0,0 +1,150 @@
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readTree reads every file under the directory by its relative path
+func readTree(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, fileInfo os.FileInfo, err error) error {
+		if err != nil || fileInfo.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[relPath], err = ioutil.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// convertTo reads the source dataset and writes it in the format under the
+// directory
+func convertTo(t *testing.T, source *Format, sourcePath string, format *Format, dir string) {
+	t.Helper()
+
+	var dataset Dataset
+	if err := source.Reader.ReadDataset(&dataset, sourcePath); err != nil {
+		t.Fatal(err)
+	}
+
+	outputPath := filepath.Join(dir, "dataset")
+	if format.Extension != "" {
+		outputPath += format.Extension
+	}
+	if err := format.Writer.WriteDataset(&dataset, outputPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertIsDeterministic(t *testing.T) {
+	sources := []struct {
+		format string
+		path   string
+	}{
+		{"voc", filepath.Join("testdata", "voc")},
+		{"coco", filepath.Join("testdata", "coco.json")},
+	}
+
+	for _, source := range sources {
+		sourceFormat, err := LookupFormat(source.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, format := range Formats() {
+			if format.Writer == nil {
+				continue
+			}
+
+			t.Run(source.format+"-to-"+format.Name, func(t *testing.T) {
+				first, second := t.TempDir(), t.TempDir()
+				convertTo(t, sourceFormat, source.path, format, first)
+				convertTo(t, sourceFormat, source.path, format, second)
+
+				firstFiles, secondFiles := readTree(t, first), readTree(t, second)
+				if len(firstFiles) == 0 || len(firstFiles) != len(secondFiles) {
+					t.Fatalf("wrote %v files and then %v files", len(firstFiles), len(secondFiles))
+				}
+				for path, content := range firstFiles {
+					if !bytes.Equal(content, secondFiles[path]) {
+						t.Errorf("%v differs between the conversions:\n%s\n%s", path, content, secondFiles[path])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVOCAttributesRoundTrip(t *testing.T) {
+	var want VOCAnnotations
+	if err := ReadVOCAnnotationFromDir(&want, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+	vocFormat, err := LookupFormat("voc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"coco", "createml"} {
+		t.Run(name, func(t *testing.T) {
+			format, err := LookupFormat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the images of the json file are next to it
+			dir := t.TempDir()
+			for _, image := range []string{"a.jpg", "b.jpg"} {
+				imageBytes, err := ioutil.ReadFile(filepath.Join("testdata", "voc", image))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(filepath.Join(dir, image), imageBytes, 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			convertTo(t, vocFormat, filepath.Join("testdata", "voc"), format, dir)
+
+			var dataset Dataset
+			if err := format.Reader.ReadDataset(&dataset, filepath.Join(dir, "dataset"+format.Extension)); err != nil {
+				t.Fatal(err)
+			}
+			var got VOCAnnotations
+			if err := EncodeVOCAnnotations(&got, &dataset); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %v annotations, want %v", len(got), len(want))
+			}
+			for index := range want {
+				if len(got[index].Object) != len(want[index].Object) {
+					t.Fatalf("%v: got %v objects, want %v", want[index].Filename, len(got[index].Object), len(want[index].Object))
+				}
+				for objIndex, wantObj := range want[index].Object {
+					gotObj := got[index].Object[objIndex]
+					if wantObj.Pose == "" {
+						wantObj.Pose = Unspecified
+					}
+					if gotObj.Pose != wantObj.Pose || gotObj.Truncated != wantObj.Truncated ||
+						gotObj.Difficult != wantObj.Difficult || gotObj.Occluded != wantObj.Occluded {
+						t.Errorf("%v: object %v = %+v, want %+v", want[index].Filename, objIndex, gotObj, wantObj)
+					}
+				}
+			}
+		})
+	}
+}
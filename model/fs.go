@@ -0,0 +1,72 @@
+package model
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// osDirFS is the os file system rooted at the directory, unlike os.DirFS the
+// errors it returns keep the os paths
+type osDirFS string
+
+func (dir osDirFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Open(dir.path(name))
+}
+
+// path returns the os path of the name in the file system
+func (dir osDirFS) path(name string) string {
+	return filepath.Join(string(dir), filepath.FromSlash(name))
+}
+
+// osPathFS returns the os file system holding the path and the name of the
+// path in it, a directory is the root of the file system itself
+func osPathFS(path string) (osDirFS, string) {
+	if fileInfo, err := os.Stat(path); err == nil && fileInfo.IsDir() {
+		return osDirFS(path), "."
+	}
+	return osDirFS(filepath.Dir(path)), filepath.Base(path)
+}
+
+// fsPath returns the path of the name to show in the messages, the os path
+// when the file system is the os one
+func fsPath(fsys fs.FS, name string) string {
+	if dir, ok := fsys.(osDirFS); ok {
+		return dir.path(name)
+	}
+	return name
+}
+
+// readImageSizeFS reads the image size of the name in the file system, the
+// names in the os file system are joined as os paths so that they may leave
+// its root like "../images/a.jpg"
+func readImageSizeFS(fsys fs.FS, name string) (int, int, error) {
+	if dir, ok := fsys.(osDirFS); ok {
+		return ReadImageSize(dir.path(name))
+	}
+	return ReadImageSizeFS(fsys, name)
+}
+
+// readDatasetFromOS reads the dataset at the os path with the file system
+// reader, the image directory of the dataset is turned into an os path
+func readDatasetFromOS(dataset *Dataset, path string, read func(dataset *Dataset, fsys fs.FS, path string) error) error {
+	fsys, name := osPathFS(path)
+	if err := read(dataset, fsys, name); err != nil {
+		return err
+	}
+
+	dataset.ImageDir = fsys.path(dataset.ImageDir)
+	dataset.ImageFS = nil
+	return nil
+}
+
+// detectFromOS adapts the file system detection of a format to os paths
+func detectFromOS(detect func(fsys fs.FS, path string) bool) func(path string) bool {
+	return func(path string) bool {
+		fsys, name := osPathFS(path)
+		return detect(fsys, name)
+	}
+}
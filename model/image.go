@@ -0,0 +1,242 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// ReadImageSize reads the width and height of the image from its header, as
+// the image is displayed after the rotation of its exif orientation
+func ReadImageSize(path string) (int, int, error) {
+	imageFile, err := os.Open(path)
+	if err != nil {
+		return 0, 0, imageOpenError(path, err)
+	}
+	defer imageFile.Close()
+
+	return readImageSize(imageFile, path)
+}
+
+// ReadImageSizeFS is like ReadImageSize but reads the image from the file system
+func ReadImageSizeFS(fsys fs.FS, path string) (int, int, error) {
+	imageFile, err := fsys.Open(path)
+	if err != nil {
+		return 0, 0, imageOpenError(fsPath(fsys, path), err)
+	}
+	defer imageFile.Close()
+
+	// the orientation is read at offsets, the files without random access
+	// are read into memory
+	reader, ok := imageFile.(imageReader)
+	if !ok {
+		imageBytes, err := ioutil.ReadAll(imageFile)
+		if err != nil {
+			return 0, 0, fmt.Errorf("image [%v] reading... %v", fsPath(fsys, path), err.Error())
+		}
+		reader = bytes.NewReader(imageBytes)
+	}
+	return readImageSize(reader, fsPath(fsys, path))
+}
+
+// imageReader is an image file read both in sequence and at offsets
+type imageReader interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+func readImageSize(imageFile imageReader, path string) (int, int, error) {
+	imageConfig, format, err := image.DecodeConfig(imageFile)
+	if err != nil {
+		return 0, 0, fmt.Errorf("image [%v] reading... %v", path, err.Error())
+	}
+
+	width, height := imageConfig.Width, imageConfig.Height
+	if _, err := imageFile.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("image [%v] reading... %v", path, err.Error())
+	}
+	// the orientations 5 to 8 turn the image by 90 degrees
+	if orientation := readImageOrientation(imageFile, format); orientation >= 5 && orientation <= 8 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// readImageOrientation returns the exif orientation of the image from 1 to 8,
+// 0 if the image has none or it can not be read
+func readImageOrientation(imageFile imageReader, format string) int {
+	var exif []byte
+	switch format {
+	case "jpeg":
+		exif = readJPEGExif(bufio.NewReader(imageFile))
+	case "png":
+		exif = readPNGExif(bufio.NewReader(imageFile))
+	case "webp":
+		exif = readWebPExif(bufio.NewReader(imageFile))
+	case "tiff":
+		// a tiff file has the orientation tag of its own
+		return readTIFFOrientation(imageFile)
+	}
+
+	if exif == nil {
+		return 0
+	}
+	return readTIFFOrientation(bytes.NewReader(exif))
+}
+
+// the exif header of the jpeg app1 segment and the webp exif chunk
+var exifHeader = []byte("Exif\x00\x00")
+
+// readJPEGExif returns the tiff data of the exif app1 segment, which comes
+// before the image data
+func readJPEGExif(r *bufio.Reader) []byte {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil || soi != [2]byte{0xff, 0xd8} {
+		return nil
+	}
+
+	for {
+		b, err := r.ReadByte()
+		if err != nil || b != 0xff {
+			return nil
+		}
+		marker, err := r.ReadByte()
+		for err == nil && marker == 0xff {
+			marker, err = r.ReadByte()
+		}
+		if err != nil {
+			return nil
+		}
+
+		switch {
+		case marker == 0xd9 || marker == 0xda:
+			// the end of image or the start of scan
+			return nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// the markers without segment
+			continue
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil || length < 2 {
+			return nil
+		}
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil
+		}
+		if marker == 0xe1 && bytes.HasPrefix(segment, exifHeader) {
+			return segment[len(exifHeader):]
+		}
+	}
+}
+
+// readPNGExif returns the data of the exif chunk, which comes before the
+// image data
+func readPNGExif(r *bufio.Reader) []byte {
+	if _, err := r.Discard(8); err != nil {
+		return nil
+	}
+
+	for {
+		var header struct {
+			Length uint32
+			Type   [4]byte
+		}
+		if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+			return nil
+		}
+		chunkType := string(header.Type[:])
+		if chunkType == "IDAT" || chunkType == "IEND" || header.Length > 1<<24 {
+			return nil
+		}
+
+		// the data is followed by the crc
+		data := make([]byte, header.Length+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil
+		}
+		if chunkType == "eXIf" {
+			return data[:header.Length]
+		}
+	}
+}
+
+// readWebPExif returns the data of the exif chunk of the extended webp
+func readWebPExif(r *bufio.Reader) []byte {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil || string(riff[8:]) != "WEBP" {
+		return nil
+	}
+
+	for {
+		var header struct {
+			Type   [4]byte
+			Length uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &header); err != nil || header.Length > 1<<24 {
+			return nil
+		}
+
+		// the chunks are padded to even lengths
+		data := make([]byte, header.Length+header.Length%2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil
+		}
+		if string(header.Type[:]) == "EXIF" {
+			return bytes.TrimPrefix(data[:header.Length], exifHeader)
+		}
+	}
+}
+
+// readTIFFOrientation returns the orientation tag of the first ifd of the
+// tiff data, 0 if there is none
+func readTIFFOrientation(r io.ReaderAt) int {
+	const orientationTag = 0x0112
+
+	var header [8]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(header[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int64(order.Uint32(header[4:]))
+	var count [2]byte
+	if _, err := r.ReadAt(count[:], offset); err != nil {
+		return 0
+	}
+
+	// every entry is the tag, type, count and value of 12 bytes
+	entries := int(order.Uint16(count[:]))
+	for index := 0; index < entries && index < 1024; index++ {
+		var entry [12]byte
+		if _, err := r.ReadAt(entry[:], offset+2+int64(index)*12); err != nil {
+			return 0
+		}
+		if order.Uint16(entry[:2]) == orientationTag {
+			return int(order.Uint16(entry[8:10]))
+		}
+	}
+	return 0
+}
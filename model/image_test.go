@@ -0,0 +1,176 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// the images are 4 pixels wide and 2 high before the rotation
+const (
+	testImageWidth  = 4
+	testImageHeight = 2
+)
+
+func TestReadImageSize(t *testing.T) {
+	encoders := map[string]func(t *testing.T, orientation int) []byte{
+		"jpeg": encodeTestJPEG,
+		"png":  encodeTestPNG,
+		"webp": encodeTestWebP,
+		"tiff": encodeTestTIFF,
+	}
+	// no orientation, upside down and turned clockwise and counterclockwise
+	tests := []struct {
+		orientation int
+		width       int
+		height      int
+	}{
+		{0, testImageWidth, testImageHeight},
+		{3, testImageWidth, testImageHeight},
+		{6, testImageHeight, testImageWidth},
+		{8, testImageHeight, testImageWidth},
+	}
+	for format, encode := range encoders {
+		for _, test := range tests {
+			data := encode(t, test.orientation)
+			width, height, err := ReadImageSizeFS(fstest.MapFS{"a": &fstest.MapFile{Data: data}}, "a")
+			if err != nil {
+				t.Errorf("%v with orientation %v: %v", format, test.orientation, err)
+			} else if width != test.width || height != test.height {
+				t.Errorf("size of %v with orientation %v = %vx%v, want %vx%v", format, test.orientation, width, height, test.width, test.height)
+			}
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	if err := ioutil.WriteFile(path, encodeTestJPEG(t, 6), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if width, height, err := ReadImageSize(path); err != nil || width != testImageHeight || height != testImageWidth {
+		t.Errorf("size of the turned jpeg file = %vx%v, %v, want %vx%v", width, height, err, testImageHeight, testImageWidth)
+	}
+}
+
+// encodeTestExif returns the tiff data holding the orientation, nil for 0
+func encodeTestExif(orientation int) []byte {
+	if orientation == 0 {
+		return nil
+	}
+	var exif bytes.Buffer
+	exif.WriteString("MM\x00*")
+	binary.Write(&exif, binary.BigEndian, uint32(8))
+	binary.Write(&exif, binary.BigEndian, uint16(1))
+	// the orientation is a short
+	binary.Write(&exif, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&exif, binary.BigEndian, uint32(1))
+	binary.Write(&exif, binary.BigEndian, []uint16{uint16(orientation), 0})
+	binary.Write(&exif, binary.BigEndian, uint32(0))
+	return exif.Bytes()
+}
+
+func encodeTestJPEG(t *testing.T, orientation int) []byte {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewGray(image.Rect(0, 0, testImageWidth, testImageHeight)), nil); err != nil {
+		t.Fatal(err)
+	}
+	exif := encodeTestExif(orientation)
+	if exif == nil {
+		return encoded.Bytes()
+	}
+
+	// the app1 segment follows the start of image
+	segment := append(append([]byte{}, exifHeader...), exif...)
+	var data bytes.Buffer
+	data.Write(encoded.Bytes()[:2])
+	data.Write([]byte{0xff, 0xe1})
+	binary.Write(&data, binary.BigEndian, uint16(len(segment)+2))
+	data.Write(segment)
+	data.Write(encoded.Bytes()[2:])
+	return data.Bytes()
+}
+
+func encodeTestPNG(t *testing.T, orientation int) []byte {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewGray(image.Rect(0, 0, testImageWidth, testImageHeight))); err != nil {
+		t.Fatal(err)
+	}
+	exif := encodeTestExif(orientation)
+	if exif == nil {
+		return encoded.Bytes()
+	}
+
+	// the exif chunk follows the signature and the header chunk of 25 bytes
+	chunk := append([]byte("eXIf"), exif...)
+	var data bytes.Buffer
+	data.Write(encoded.Bytes()[:8+25])
+	binary.Write(&data, binary.BigEndian, uint32(len(exif)))
+	data.Write(chunk)
+	binary.Write(&data, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	data.Write(encoded.Bytes()[8+25:])
+	return data.Bytes()
+}
+
+// encodeTestWebP returns an extended webp, whose size is read from the VP8X
+// chunk
+func encodeTestWebP(t *testing.T, orientation int) []byte {
+	var chunks bytes.Buffer
+	chunks.WriteString("VP8X")
+	binary.Write(&chunks, binary.LittleEndian, uint32(10))
+	chunks.Write([]byte{0x08, 0, 0, 0, testImageWidth - 1, 0, 0, testImageHeight - 1, 0, 0})
+	if exif := encodeTestExif(orientation); exif != nil {
+		chunks.WriteString("EXIF")
+		binary.Write(&chunks, binary.LittleEndian, uint32(len(exif)))
+		chunks.Write(exif)
+	}
+
+	var data bytes.Buffer
+	data.WriteString("RIFF")
+	binary.Write(&data, binary.LittleEndian, uint32(4+chunks.Len()))
+	data.WriteString("WEBP")
+	data.Write(chunks.Bytes())
+	return data.Bytes()
+}
+
+// encodeTestTIFF returns an uncompressed gray tiff with the orientation tag
+func encodeTestTIFF(t *testing.T, orientation int) []byte {
+	entries := [][3]uint32{
+		{256, 3, testImageWidth},
+		{257, 3, testImageHeight},
+		{258, 3, 8},
+		{259, 3, 1},
+		{262, 3, 1},
+		{273, 4, 0},
+		{278, 3, testImageHeight},
+		{279, 4, testImageWidth * testImageHeight},
+	}
+	if orientation != 0 {
+		entries = append(entries[:6], append([][3]uint32{{0x0112, 3, uint32(orientation)}}, entries[6:]...)...)
+	}
+	// the pixels follow the ifd
+	pixels := 8 + 2 + 12*len(entries) + 4
+	entries[5][2] = uint32(pixels)
+
+	var data bytes.Buffer
+	data.WriteString("II*\x00")
+	binary.Write(&data, binary.LittleEndian, uint32(8))
+	binary.Write(&data, binary.LittleEndian, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(&data, binary.LittleEndian, []uint16{uint16(entry[0]), uint16(entry[1])})
+		binary.Write(&data, binary.LittleEndian, uint32(1))
+		if entry[1] == 3 {
+			binary.Write(&data, binary.LittleEndian, []uint16{uint16(entry[2]), 0})
+		} else {
+			binary.Write(&data, binary.LittleEndian, entry[2])
+		}
+	}
+	binary.Write(&data, binary.LittleEndian, uint32(0))
+	data.Write(make([]byte, testImageWidth*testImageHeight))
+	return data.Bytes()
+}
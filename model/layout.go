@@ -0,0 +1,228 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// the ways to place the image files of a written dataset
+const (
+	ImagesNone     = "none"
+	ImagesCopy     = "copy"
+	ImagesSymlink  = "symlink"
+	ImagesHardlink = "hardlink"
+)
+
+// DatasetLayoutWriter is implemented by the writers which know where the
+// image files go next to the annotations, like the JPEGImages of PascalVOC
+type DatasetLayoutWriter interface {
+	// ImageDir returns the directory of the image files of the dataset
+	// written to the path
+	ImageDir(path string) string
+
+	// WriteDatasetLayout writes the dataset whose image file names are
+	// relative to ImageDir(path)
+	WriteDatasetLayout(dataset *Dataset, path string) error
+}
+
+// WriteDatasetWithImages writes the dataset in the format to the path and
+// places its image files in the layout of the format by copying or linking
+// them, the file names in the written annotations are rewritten to the
+// placed files. The images are left where they are with ImagesNone.
+func WriteDatasetWithImages(format *Format, dataset *Dataset, path string, mode string) error {
+	return writeDatasetWithImages(format, dataset, path, mode, false)
+}
+
+// WriteDatasetWithNamedImages is like WriteDatasetWithImages but the images
+// keep their file names under the image directory of the layout, e.g. the
+// names given by MergeDatasets, only the names leaving the directory are
+// flattened
+func WriteDatasetWithNamedImages(format *Format, dataset *Dataset, path string, mode string) error {
+	return writeDatasetWithImages(format, dataset, path, mode, true)
+}
+
+func writeDatasetWithImages(format *Format, dataset *Dataset, path string, mode string, keepNames bool) error {
+	if format.Writer == nil {
+		return fmt.Errorf("the format %v can not be written", format.Name)
+	}
+
+	switch mode {
+	case ImagesNone, "":
+		return format.Writer.WriteDataset(dataset, path)
+	case ImagesCopy, ImagesSymlink, ImagesHardlink:
+	default:
+		return fmt.Errorf("unknown images [%v], %v, %v, %v or %v", mode, ImagesCopy, ImagesSymlink, ImagesHardlink, ImagesNone)
+	}
+	if dataset.ImageFS != nil && mode != ImagesCopy {
+		return fmt.Errorf("the images of a file system can only be copied, not %v", mode)
+	}
+
+	layoutWriter, ok := format.Writer.(DatasetLayoutWriter)
+	if !ok {
+		return fmt.Errorf("the format %v has no image layout", format.Name)
+	}
+	imageDir := layoutWriter.ImageDir(path)
+
+	placed := *dataset
+	placed.Images = make([]DatasetImage, len(dataset.Images))
+	placed.ImageDir = imageDir
+	placed.ImageFS = nil
+
+	sources := make([]string, len(dataset.Images))
+	names := make(map[string]bool, len(dataset.Images))
+	for index := range dataset.Images {
+		image := dataset.Images[index]
+		sources[index] = dataset.ImagePath(&image)
+
+		image.FileName = placedImageName(&image, names, keepNames)
+		image.Path = ""
+		if image.Attributes["path"] != "" {
+			// the original path of the voc annotation is no longer true
+			attributes := make(map[string]string, len(image.Attributes))
+			for key, value := range image.Attributes {
+				if key != "path" {
+					attributes[key] = value
+				}
+			}
+			image.Attributes = attributes
+		}
+		placed.Images[index] = image
+	}
+
+	// the images are placed with LoadJobs workers
+	err := loadParallel(len(sources), func(index int) error {
+		target := filepath.Join(imageDir, filepath.FromSlash(placed.Images[index].FileName))
+		return placeImage(dataset.ImageFS, sources[index], target, mode)
+	})
+	if err != nil {
+		return err
+	}
+
+	return layoutWriter.WriteDatasetLayout(&placed, path)
+}
+
+// placedImageName returns the name of the image in the image directory of
+// the layout, the base of its file name or the whole name if it is kept,
+// with the image ID appended when the name is taken by another image, and a
+// counter after it while the renamed one is taken as well
+func placedImageName(image *DatasetImage, names map[string]bool, keepName bool) string {
+	fileName := filepath.ToSlash(image.FileName)
+	if !keepName || !fs.ValidPath(fileName) {
+		fileName = path.Base(fileName)
+	}
+	ext := path.Ext(fileName)
+	stem := strings.TrimSuffix(fileName, ext)
+
+	name := fileName
+	for count := 1; names[name]; count++ {
+		if count == 1 {
+			name = fmt.Sprintf("%v_%d%v", stem, image.ID, ext)
+		} else {
+			name = fmt.Sprintf("%v_%d_%d%v", stem, image.ID, count, ext)
+		}
+	}
+	names[name] = true
+	return name
+}
+
+// placeImage copies or links the image file at the source to the target,
+// the source is in the file system if it is not nil
+func placeImage(fsys fs.FS, source string, target string, mode string) error {
+	var sourceInfo fs.FileInfo
+	var err error
+	if fsys != nil {
+		sourceInfo, err = fs.Stat(fsys, source)
+	} else {
+		sourceInfo, err = os.Stat(source)
+	}
+	if err != nil {
+		return imageOpenError(fsPath(fsys, source), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	if targetInfo, err := os.Lstat(target); err == nil {
+		// the image is already in place, e.g. written to its own directory
+		if sameImageFile(fsys, source, sourceInfo, target, targetInfo) {
+			return nil
+		}
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	switch mode {
+	case ImagesSymlink:
+		absSource, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(absSource, target)
+	case ImagesHardlink:
+		return os.Link(source, target)
+	default:
+		return copyImage(fsys, source, target)
+	}
+}
+
+// sameImageFile reports whether the existing target is the source image
+// itself rather than a file to replace, it is never removed then
+func sameImageFile(fsys fs.FS, source string, sourceInfo fs.FileInfo, target string, targetInfo fs.FileInfo) bool {
+	if os.SameFile(sourceInfo, targetInfo) {
+		return true
+	}
+
+	var sourcePath string
+	switch dir := fsys.(type) {
+	case nil:
+		sourcePath = source
+	case osDirFS:
+		sourcePath = dir.path(source)
+	default:
+		// the os path of the source is unknown, e.g. of os.DirFS, a link
+		// at the target may be the source itself
+		linkedInfo, err := os.Stat(target)
+		return err == nil && os.SameFile(sourceInfo, linkedInfo)
+	}
+
+	absSource, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return true
+	}
+	absTarget, err := filepath.Abs(target)
+	return err != nil || absSource == absTarget
+}
+
+// copyImage copies the image file at the source to the target
+func copyImage(fsys fs.FS, source string, target string) error {
+	var sourceFile io.ReadCloser
+	var err error
+	if fsys != nil {
+		sourceFile, err = fsys.Open(source)
+	} else {
+		sourceFile, err = os.Open(source)
+	}
+	if err != nil {
+		return imageOpenError(fsPath(fsys, source), err)
+	}
+	defer sourceFile.Close()
+
+	targetFile, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(targetFile, sourceFile); err != nil {
+		targetFile.Close()
+		return fmt.Errorf("image [%v] copying... %w", fsPath(fsys, source), err)
+	}
+	return targetFile.Close()
+}
@@ -0,0 +1,181 @@
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteDatasetWithImages(t *testing.T) {
+	var source Dataset
+	if err := ReadDatasetFromPascalVOCDir(&source, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+
+	layouts := []struct {
+		format string
+		output string
+		images []string
+	}{
+		{"coco", "dataset.json", []string{"images/a.jpg", "images/b.jpg"}},
+		{"createml", "dataset.json", []string{"a.jpg", "b.jpg"}},
+		{"yolo", "dataset", []string{"dataset/images/a.jpg", "dataset/images/b.jpg"}},
+		{"voc", "dataset", []string{"dataset/JPEGImages/a.jpg", "dataset/JPEGImages/b.jpg"}},
+	}
+	for _, layout := range layouts {
+		for _, mode := range []string{ImagesCopy, ImagesSymlink, ImagesHardlink} {
+			t.Run(layout.format+"-"+mode, func(t *testing.T) {
+				format, err := LookupFormat(layout.format)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				dir := t.TempDir()
+				if err := WriteDatasetWithImages(format, &source, filepath.Join(dir, layout.output), mode); err != nil {
+					t.Fatal(err)
+				}
+				for index, image := range layout.images {
+					got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(image)))
+					if err != nil {
+						t.Fatal(err)
+					}
+					want, err := ioutil.ReadFile(source.ImagePath(&source.Images[index]))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, want) {
+						t.Errorf("%v differs from the source image", image)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWriteDatasetWithImagesRenamesCollisions(t *testing.T) {
+	image, err := ioutil.ReadFile(filepath.Join("testdata", "voc", "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceDir := t.TempDir()
+	for _, dir := range []string{"day", "night"} {
+		if err := os.MkdirAll(filepath.Join(sourceDir, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, dir, "a.jpg"), image, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dataset := Dataset{
+		Images: []DatasetImage{
+			{ID: 1, FileName: "day/a.jpg", Width: 100, Height: 80},
+			{ID: 2, FileName: "night/a.jpg", Width: 100, Height: 80},
+		},
+		ImageDir: sourceDir,
+	}
+	format, err := LookupFormat("createml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(t.TempDir(), "dataset.json")
+	if err := WriteDatasetWithImages(format, &dataset, outputPath, ImagesCopy); err != nil {
+		t.Fatal(err)
+	}
+
+	var written CreateMLAnnotations
+	if err := ReadCreateMLAnnotationsFromFile(&written, outputPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 2 || written[0].Image != "a.jpg" || written[1].Image != "a_2.jpg" {
+		t.Fatalf("written images = %+v, want a.jpg and a_2.jpg", written)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(outputPath), "a_2.jpg")); err != nil {
+		t.Error(err)
+	}
+	if dataset.Images[1].FileName != "night/a.jpg" {
+		t.Errorf("the source dataset is changed: %v", dataset.Images[1].FileName)
+	}
+}
+
+func TestWriteDatasetWithImagesKeepsSourceImages(t *testing.T) {
+	image, err := ioutil.ReadFile(filepath.Join("testdata", "voc", "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, err := LookupFormat("createml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the images of a createml file are next to it, writing another one to
+	// the same directory places the images onto themselves
+	readers := map[string]func(dataset *Dataset, dir string) error{
+		"os": func(dataset *Dataset, dir string) error {
+			return ReadDatasetFromCreateMLFile(dataset, filepath.Join(dir, "dataset.json"))
+		},
+		"osDirFS": func(dataset *Dataset, dir string) error {
+			return ReadDatasetFromCreateMLFS(dataset, osDirFS(dir), "dataset.json")
+		},
+		"os.DirFS": func(dataset *Dataset, dir string) error {
+			return ReadDatasetFromCreateMLFS(dataset, os.DirFS(dir), "dataset.json")
+		},
+	}
+	for name, read := range readers {
+		for _, mode := range []string{ImagesCopy, ImagesSymlink, ImagesHardlink} {
+			t.Run(name+"-"+mode, func(t *testing.T) {
+				dir := t.TempDir()
+				if err := ioutil.WriteFile(filepath.Join(dir, "a.jpg"), image, 0666); err != nil {
+					t.Fatal(err)
+				}
+				createML := `[{"image": "a.jpg", "annotations": []}]`
+				if err := ioutil.WriteFile(filepath.Join(dir, "dataset.json"), []byte(createML), 0666); err != nil {
+					t.Fatal(err)
+				}
+
+				var dataset Dataset
+				if err := read(&dataset, dir); err != nil {
+					t.Fatal(err)
+				}
+				err := WriteDatasetWithImages(format, &dataset, filepath.Join(dir, "written.json"), mode)
+				if dataset.ImageFS != nil && mode != ImagesCopy {
+					if err == nil {
+						t.Fatal("the images of a file system are linked")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := ioutil.ReadFile(filepath.Join(dir, "a.jpg"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, image) {
+					t.Error("the source image is changed")
+				}
+			})
+		}
+	}
+}
+
+func TestPlacedImageName(t *testing.T) {
+	images := []DatasetImage{
+		{ID: 1, FileName: "day/a.jpg"},
+		{ID: 9, FileName: "a_2.jpg"},
+		{ID: 2, FileName: "night/a.jpg"},
+		{ID: 3, FileName: "a_2_2.jpg"},
+		{ID: 2, FileName: "dusk/a.jpg"},
+	}
+	want := []string{"a.jpg", "a_2.jpg", "a_2_2.jpg", "a_2_2_3.jpg", "a_2_3.jpg"}
+
+	names := make(map[string]bool)
+	for index := range images {
+		if got := placedImageName(&images[index], names, false); got != want[index] {
+			t.Errorf("name of %v = %v, want %v", images[index].FileName, got, want[index])
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the ways to resolve the same file name in several sources
+const (
+	// the later file names get the source name as prefix, e.g. b_0001.jpg
+	CollisionPrefix = "prefix"
+
+	// the later file names are moved under the source name, e.g. b/0001.jpg
+	CollisionSubdir = "subdir"
+)
+
+// MergeSource is a dataset to merge with the name telling it apart
+type MergeSource struct {
+	Name    string
+	Format  string
+	Path    string
+	Dataset *Dataset
+}
+
+type MergeOptions struct {
+	// the category names replacing the source names, the categories with the
+	// same name after the mapping become one
+	Mapping map[string]string
+
+	// CollisionPrefix or CollisionSubdir
+	Collision string
+}
+
+// MergeContribution is what a source brings into the merged dataset
+type MergeContribution struct {
+	Name          string `json:"name" yaml:"name"`
+	Format        string `json:"format" yaml:"format"`
+	Path          string `json:"path" yaml:"path"`
+	Images        int    `json:"images" yaml:"images"`
+	Annotations   int    `json:"annotations" yaml:"annotations"`
+	RenamedImages int    `json:"renamed_images" yaml:"renamed_images"`
+
+	// the instances of every category of the merged dataset
+	Instances map[string]int `json:"instances" yaml:"instances"`
+}
+
+type MergeReport struct {
+	Images      int                 `json:"images" yaml:"images"`
+	Annotations int                 `json:"annotations" yaml:"annotations"`
+	Categories  []string            `json:"categories" yaml:"categories"`
+	Sources     []MergeContribution `json:"sources" yaml:"sources"`
+}
+
+// MergeDatasets combines the sources into a new dataset: the categories are
+// unified by name after the mapping in the order they first appear, the
+// licenses by name and url, the images and annotations get new IDs in the
+// order of the sources, and a file name already taken by an earlier source is
+// renamed by the collision option. The merged images keep the paths of their
+// files in the sources, WriteDatasetWithNamedImages places the files under
+// the merged names.
+func MergeDatasets(sources []MergeSource, options MergeOptions) (*Dataset, MergeReport, error) {
+	if options.Collision == "" {
+		options.Collision = CollisionPrefix
+	}
+	if options.Collision != CollisionPrefix && options.Collision != CollisionSubdir {
+		return nil, MergeReport{}, fmt.Errorf("unknown collision [%v], %v or %v", options.Collision, CollisionPrefix, CollisionSubdir)
+	}
+
+	merged := &Dataset{
+		Licenses:    make([]DatasetLicense, 0),
+		Categories:  make([]DatasetCategory, 0),
+		Images:      make([]DatasetImage, 0),
+		Annotations: make([]DatasetAnnotation, 0),
+		Metadata:    make(map[string]string),
+	}
+	report := MergeReport{Sources: make([]MergeContribution, 0, len(sources))}
+
+	sourceNames := make(map[string]bool, len(sources))
+	fileNames := make(map[string]bool)
+	for _, source := range sources {
+		// the names tell the sources apart in the renamed files
+		name := source.Name
+		for suffix := 2; sourceNames[name]; suffix++ {
+			name = fmt.Sprintf("%v_%d", source.Name, suffix)
+		}
+		sourceNames[name] = true
+
+		contribution := MergeContribution{
+			Name:      name,
+			Format:    source.Format,
+			Path:      source.Path,
+			Instances: make(map[string]int),
+		}
+		dataset := source.Dataset
+
+		categoryIDs := make(map[int]int, len(dataset.Categories))
+		categoryNames := make(map[int]string, len(dataset.Categories))
+		for _, category := range dataset.Categories {
+			categoryName := category.Name
+			if mappedName, ok := options.Mapping[categoryName]; ok {
+				categoryName = mappedName
+			}
+			categoryIDs[category.ID] = merged.AddCategory(categoryName)
+			categoryNames[category.ID] = categoryName
+
+			// the first super category given to the name is kept
+			for index := range merged.Categories {
+				if merged.Categories[index].Name == categoryName && merged.Categories[index].SuperCategory == "" {
+					merged.Categories[index].SuperCategory = category.SuperCategory
+				}
+			}
+		}
+
+		licenseIDs := make(map[int]int, len(dataset.Licenses))
+		for _, license := range dataset.Licenses {
+			licenseIDs[license.ID] = mergeLicense(merged, license)
+		}
+
+		imageIDs := make(map[int]int, len(dataset.Images))
+		for _, image := range dataset.Images {
+			imagePath := dataset.ImagePath(&image)
+			if fileNames[image.FileName] {
+				fileName := renameMergedFile(image.FileName, name, options.Collision)
+				for suffix := 2; fileNames[fileName]; suffix++ {
+					fileName = renameMergedFile(image.FileName, fmt.Sprintf("%v_%d", name, suffix), options.Collision)
+				}
+				image.FileName = fileName
+				contribution.RenamedImages++
+			}
+			fileNames[image.FileName] = true
+
+			image.License = licenseIDs[image.License]
+			image.Path = imagePath
+			imageIDs[image.ID] = merged.appendImage(image)
+			contribution.Images++
+		}
+
+		for _, annotation := range dataset.Annotations {
+			imageID, ok := imageIDs[annotation.ImageID]
+			if !ok {
+				return nil, MergeReport{}, &DanglingReferenceError{Kind: ReferenceImage, ID: annotation.ImageID, AnnotationID: annotation.ID, Dataset: name}
+			}
+			categoryID, ok := categoryIDs[annotation.CategoryID]
+			if !ok {
+				return nil, MergeReport{}, &DanglingReferenceError{Kind: ReferenceCategory, ID: annotation.CategoryID, AnnotationID: annotation.ID, Dataset: name}
+			}
+
+			contribution.Annotations++
+			contribution.Instances[categoryNames[annotation.CategoryID]]++
+
+			annotation.ImageID = imageID
+			annotation.CategoryID = categoryID
+			merged.appendAnnotation(annotation)
+		}
+
+		report.Sources = append(report.Sources, contribution)
+	}
+
+	report.Images = len(merged.Images)
+	report.Annotations = len(merged.Annotations)
+	report.Categories = make([]string, len(merged.Categories))
+	for index, category := range merged.Categories {
+		report.Categories[index] = category.Name
+	}
+	return merged, report, nil
+}
+
+// mergeLicense returns the ID of the same license in the merged dataset,
+// the license is appended with the next ID when it is not there yet
+func mergeLicense(merged *Dataset, license DatasetLicense) int {
+	for _, mergedLicense := range merged.Licenses {
+		if mergedLicense.Name == license.Name && mergedLicense.URL == license.URL {
+			return mergedLicense.ID
+		}
+	}
+
+	license.ID = len(merged.Licenses) + 1
+	merged.Licenses = append(merged.Licenses, license)
+	return license.ID
+}
+
+// renameMergedFile renames the file name taken by an earlier source
+func renameMergedFile(fileName string, sourceName string, collision string) string {
+	if collision == CollisionSubdir {
+		return sourceName + "/" + fileName
+	}
+	index := strings.LastIndex(fileName, "/") + 1
+	return fileName[:index] + sourceName + "_" + fileName[index:]
+}
+
+// ReadCategoryMappingFromFile reads the mapping from the source category names
+// to the new names, a yaml or json object
+func ReadCategoryMappingFromFile(mapping *map[string]string, path string) error {
+	mappingFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer mappingFile.Close()
+
+	if err := DecodeCategoryMapping(mapping, mappingFile); err != nil {
+		return fmt.Errorf("%v reading... %v", path, err.Error())
+	}
+	return nil
+}
+
+// DecodeCategoryMapping decodes the mapping from the yaml or json object read from r
+func DecodeCategoryMapping(mapping *map[string]string, r io.Reader) error {
+	mappingBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(mappingBytes, mapping); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeDatasetsPlacesCollidingImages(t *testing.T) {
+	// both sources hold a.jpg, the one of b is the b.jpg of the voc testdata
+	sources := make([]MergeSource, 2)
+	images := make([][]byte, 2)
+	for index, name := range []string{"a", "b"} {
+		image, err := ioutil.ReadFile(filepath.Join("testdata", "voc", name+".jpg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, "a.jpg"), image, 0666); err != nil {
+			t.Fatal(err)
+		}
+		images[index] = image
+		sources[index] = MergeSource{
+			Name: name,
+			Dataset: &Dataset{
+				Categories:  []DatasetCategory{{ID: 1, Name: "car"}},
+				Images:      []DatasetImage{{ID: 1, FileName: "a.jpg", Width: 100, Height: 80}},
+				Annotations: []DatasetAnnotation{{ID: 1, ImageID: 1, CategoryID: 1, BBox: BoundingBox{X: 1, Y: 2, Width: 3, Height: 4}}},
+				ImageDir:    dir,
+			},
+		}
+	}
+
+	collisions := []struct {
+		collision string
+		format    string
+		output    string
+		files     []string
+	}{
+		{CollisionPrefix, "createml", "merged.json", []string{"a.jpg", "b_a.jpg"}},
+		{CollisionSubdir, "createml", "merged.json", []string{"a.jpg", "b/a.jpg"}},
+		{CollisionSubdir, "coco", "merged.json", []string{"images/a.jpg", "images/b/a.jpg"}},
+	}
+	for _, collision := range collisions {
+		t.Run(collision.collision+"-"+collision.format, func(t *testing.T) {
+			merged, report, err := MergeDatasets(sources, MergeOptions{Collision: collision.collision})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Sources[1].RenamedImages != 1 {
+				t.Errorf("renamed images of b = %v, want 1", report.Sources[1].RenamedImages)
+			}
+
+			format, err := LookupFormat(collision.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputPath := filepath.Join(t.TempDir(), collision.output)
+			if err := WriteDatasetWithNamedImages(format, merged, outputPath, ImagesCopy); err != nil {
+				t.Fatal(err)
+			}
+
+			var written Dataset
+			if err := format.Reader.ReadDataset(&written, outputPath); err != nil {
+				t.Fatal(err)
+			}
+			for index, file := range collision.files {
+				if written.Images[index].FileName != file {
+					t.Errorf("file name = %v, want %v", written.Images[index].FileName, file)
+				}
+				got, err := ioutil.ReadFile(written.ImagePath(&written.Images[index]))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, images[index]) {
+					t.Errorf("%v is not the image of source %v", file, sources[index].Name)
+				}
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(sources[1].Dataset.ImageDir, "a.jpg")); err != nil {
+		t.Errorf("the source image is gone: %v", err)
+	}
+}
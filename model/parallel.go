@@ -0,0 +1,115 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// the number of files read at the same time while loading a dataset
+var loadJobs int32 = int32(runtime.NumCPU())
+
+// SetLoadJobs sets the number of the files, annotations or images, read at the
+// same time while loading a dataset, the number of CPUs if jobs is below 1
+func SetLoadJobs(jobs int) {
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	atomic.StoreInt32(&loadJobs, int32(jobs))
+}
+
+// LoadJobs returns the number of the files read at the same time while loading
+// a dataset
+func LoadJobs() int {
+	return int(atomic.LoadInt32(&loadJobs))
+}
+
+// MultiError is the errors of the items failed to load, in the order of the
+// items
+type MultiError []error
+
+// the errors listed in the message of a MultiError, the others are counted
+const multiErrorListed = 10
+
+func (errs MultiError) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+
+	messages := make([]string, 0, minInt(len(errs), multiErrorListed)+1)
+	messages = append(messages, fmt.Sprintf("%d errors occurred:", len(errs)))
+	for index, err := range errs {
+		if index == multiErrorListed {
+			messages = append(messages, fmt.Sprintf("... and %d more", len(errs)-multiErrorListed))
+			break
+		}
+		messages = append(messages, "- "+err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors for errors.Is and errors.As
+func (errs MultiError) Unwrap() []error {
+	return errs
+}
+
+// Is reports whether any of the errors is the target, errors.Is only follows
+// the Unwrap of the errors since Go 1.20
+func (errs MultiError) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors matching the target like errors.As, which
+// only follows the Unwrap of the errors since Go 1.20
+func (errs MultiError) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadParallel calls load for the indexes from 0 to count-1 with LoadJobs
+// workers, load stores its result at the index so the results keep the order.
+// All the items are loaded, the errors are returned together as a MultiError.
+func loadParallel(count int, load func(index int) error) error {
+	jobs := minInt(LoadJobs(), count)
+	errs := make([]error, count)
+
+	indexes := make(chan int)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < jobs; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for index := range indexes {
+				errs[index] = load(index)
+			}
+		}()
+	}
+	for index := 0; index < count; index++ {
+		indexes <- index
+	}
+	close(indexes)
+	waitGroup.Wait()
+
+	var multiError MultiError
+	for _, err := range errs {
+		if err != nil {
+			multiError = append(multiError, err)
+		}
+	}
+	if len(multiError) == 0 {
+		return nil
+	}
+	return multiError
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLoadParallelKeepsOrderAndCollectsErrors(t *testing.T) {
+	jobs := LoadJobs()
+	defer SetLoadJobs(jobs)
+	SetLoadJobs(4)
+
+	results := make([]int, 100)
+	err := loadParallel(len(results), func(index int) error {
+		results[index] = index * index
+		if index%30 == 1 {
+			return fmt.Errorf("item %d", index)
+		}
+		return nil
+	})
+	for index, result := range results {
+		if result != index*index {
+			t.Fatalf("result %d = %d, want %d", index, result, index*index)
+		}
+	}
+
+	var multiError MultiError
+	if !errors.As(err, &multiError) {
+		t.Fatalf("error %v is not a MultiError", err)
+	}
+	want := []string{"item 1", "item 31", "item 61", "item 91"}
+	if len(multiError) != len(want) {
+		t.Fatalf("got %d errors, want %d", len(multiError), len(want))
+	}
+	for index, err := range multiError {
+		if err.Error() != want[index] {
+			t.Errorf("error %d = %q, want %q", index, err, want[index])
+		}
+	}
+}
+
+func TestMultiErrorIsAndAs(t *testing.T) {
+	missing := &MissingImageError{Path: "b.jpg", Err: fs.ErrNotExist}
+	errs := MultiError{errors.New("a.jpg"), fmt.Errorf("b.jpg reading... %w", missing)}
+
+	// the methods are called directly, errors.Is and errors.As of Go 1.18
+	// do not walk the errors of Unwrap() []error
+	if !errs.Is(fs.ErrNotExist) {
+		t.Error("the MultiError is not fs.ErrNotExist")
+	}
+	var found *MissingImageError
+	if !errs.As(&found) || found != missing {
+		t.Errorf("found %v, want the MissingImageError", found)
+	}
+	var dangling *DanglingReferenceError
+	if errs.As(&dangling) || errs.Is(fs.ErrPermission) {
+		t.Error("the MultiError matches an error it does not hold")
+	}
+
+	wrapped := fmt.Errorf("dataset reading... %w", errs)
+	if !errors.As(wrapped, &found) || !errors.Is(wrapped, fs.ErrNotExist) {
+		t.Error("the wrapped MultiError does not match its errors")
+	}
+}
+
+// concurrentFS counts the files open at the same time, each is held open
+// for a while so that the workers overlap
+type concurrentFS struct {
+	fsys fs.FS
+
+	mutex   sync.Mutex
+	open    int
+	maxOpen int
+}
+
+func (fsys *concurrentFS) Open(name string) (fs.File, error) {
+	fsys.mutex.Lock()
+	fsys.open++
+	fsys.maxOpen = maxInt(fsys.maxOpen, fsys.open)
+	fsys.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	fsys.mutex.Lock()
+	fsys.open--
+	fsys.mutex.Unlock()
+	return fsys.fsys.Open(name)
+}
+
+func TestImageChecksUseLoadJobs(t *testing.T) {
+	jobs := LoadJobs()
+	defer SetLoadJobs(jobs)
+
+	images := fstest.MapFS{}
+	dataset := &Dataset{}
+	for index := 1; index <= 8; index++ {
+		name := fmt.Sprintf("%d.jpg", index)
+		images[name] = &fstest.MapFile{Data: encodeTestJPEG(t, 0)}
+		dataset.AddImage(DatasetImage{FileName: name, Width: testImageWidth, Height: testImageHeight})
+	}
+	// the size of the last image disagrees with its file
+	dataset.Images[7].Width = 10
+
+	for _, jobs := range []int{1, 4} {
+		SetLoadJobs(jobs)
+
+		fsys := &concurrentFS{fsys: images}
+		dataset.ImageFS = fsys
+		report := ValidateDataset(dataset, ValidationOptions{CheckImages: true})
+		if report.Errors != 1 || report.Issues[0].Check != CheckSizeMismatch || report.Issues[0].Image != "8.jpg" {
+			t.Errorf("issues with %v jobs = %+v, want the size of 8.jpg", jobs, report.Issues)
+		}
+		if jobs == 1 && fsys.maxOpen != 1 || jobs > 1 && fsys.maxOpen < 2 {
+			t.Errorf("validated %v image files at the same time with %v jobs", fsys.maxOpen, jobs)
+		}
+
+		fsys = &concurrentFS{fsys: images}
+		fixed := *dataset
+		fixed.ImageFS = fsys
+		fixed.Images = append([]DatasetImage(nil), dataset.Images...)
+		changelog := FixDataset(&fixed, FixOptions{CheckImages: true})
+		if len(changelog.Changes) != 1 || changelog.Changes[0].Action != FixCorrectSize || fixed.Images[7].Width != testImageWidth {
+			t.Errorf("changes with %v jobs = %+v, want the size of 8.jpg corrected", jobs, changelog.Changes)
+		}
+		if jobs == 1 && fsys.maxOpen != 1 || jobs > 1 && fsys.maxOpen < 2 {
+			t.Errorf("fixed %v image files at the same time with %v jobs", fsys.maxOpen, jobs)
+		}
+	}
+}
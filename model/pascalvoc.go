@@ -4,14 +4,29 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"image"
-	_ "image/jpeg"
+	"io/fs"
 	"io/ioutil"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+func init() {
+	MustRegisterFormat(&Format{
+		Name:        "voc",
+		Aliases:     []string{"pascalvoc"},
+		Description: "PascalVOC",
+		Detect:      detectPascalVOCPath,
+		Reader:      DatasetReaderFunc(ReadDatasetFromPascalVOCDir),
+		Writer:      vocWriter{},
+		DetectFS:    detectPascalVOCDir,
+		FSReader:    DatasetFSReaderFunc(ReadDatasetFromPascalVOCFS),
+	})
+}
+
 type VOCDataSource struct {
 	Database   string `xml:"database"`
 	Annotation string `xml:"annotation"`
@@ -68,173 +83,330 @@ type VOCAnnotation struct {
 type VOCAnnotations []VOCAnnotation
 
 func ReadVOCAnnotationFromFile(annotation *VOCAnnotation, path string) error {
+	fsys, name := osPathFS(path)
+	return readVOCAnnotationFS(annotation, fsys, name)
+}
 
-	if pathExt := filepath.Ext(path); pathExt == "" || strings.ToLower(pathExt) != ".xml" {
-		return errors.New(path + " is not a valid xml file path")
+func readVOCAnnotationFS(annotation *VOCAnnotation, fsys fs.FS, name string) error {
+	if nameExt := path.Ext(name); nameExt == "" || strings.ToLower(nameExt) != ".xml" {
+		return errors.New(fsPath(fsys, name) + " is not a valid xml file path")
 	}
 
-	xmlBytes, readErr := ioutil.ReadFile(path)
-
-	if readErr != nil {
-		return readErr
+	xmlBytes, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
 	}
-
-	xmlStr := string(xmlBytes)
-	return xml.Unmarshal([]byte(xmlStr), annotation)
+	return xml.Unmarshal(xmlBytes, annotation)
 }
 
+// ReadVOCAnnotationFromDir reads the xml files in the directory with
+// LoadJobs workers, the annotations keep the order of the file names
 func ReadVOCAnnotationFromDir(annotations *VOCAnnotations, path string) error {
-	fileInfos, err := ioutil.ReadDir(path)
+	return ReadVOCAnnotationsFS(annotations, osDirFS(path), ".")
+}
+
+// ReadVOCAnnotationsFS is like ReadVOCAnnotationFromDir but reads the
+// directory of the file system
+func ReadVOCAnnotationsFS(annotations *VOCAnnotations, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
 	if err != nil {
 		return err
 	}
 
-	isEmpty := true
-
-	for _, fileInfo := range fileInfos {
-		fileName := fileInfo.Name()
-		filePath := filepath.Join(path, fileName)
-		if strings.ToLower(filepath.Ext(filePath)) == ".xml" {
-			var annotation VOCAnnotation
-			err := ReadVOCAnnotationFromFile(&annotation, filePath)
-			if err != nil {
-				return err
-			}
-			isEmpty = false
-			*annotations = append(*annotations, annotation)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.ToLower(path.Ext(entry.Name())) == ".xml" {
+			names = append(names, path.Join(dir, entry.Name()))
 		}
 	}
 
-	if isEmpty {
+	if len(names) == 0 {
 		return errors.New("not found xml file in the directory path")
 	}
+	return readVOCAnnotationFiles(annotations, fsys, names)
+}
+
+// readVOCAnnotationFiles reads the xml files with LoadJobs workers, the
+// annotations keep the order of the names
+func readVOCAnnotationFiles(annotations *VOCAnnotations, fsys fs.FS, names []string) error {
+	loaded := make(VOCAnnotations, len(names))
+	err := loadParallel(len(names), func(index int) error {
+		if err := readVOCAnnotationFS(&loaded[index], fsys, names[index]); err != nil {
+			return fmt.Errorf("%v reading... %w", fsPath(fsys, names[index]), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
+	*annotations = append(*annotations, loaded...)
 	return nil
 }
 
-func ReadVOCAnnotationsFromCOCOFile(annotations *VOCAnnotations, path string) error {
+// ReadVOCImageSetFromFile reads the image names of the image set list like
+// ImageSets/Main/train.txt. The images of a class list like car_train.txt
+// are kept unless they are flagged -1, i.e. without the class
+func ReadVOCImageSetFromFile(imageSet *[]string, path string) error {
+	fsys, name := osPathFS(path)
+	return readVOCImageSetFS(imageSet, fsys, name)
+}
 
-	// read the coco annotation data from json file
-	var cocoAnnotations COCOAnnotations
-	if err := ReadCOCOAnnotationsFromFile(&cocoAnnotations, path); err != nil {
+func readVOCImageSetFS(imageSet *[]string, fsys fs.FS, name string) error {
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
 		return err
 	}
 
-	// generate the image map with ID
-	imageMap := make(map[int]COCOImage)
-	annotationMap := make(map[int]VOCAnnotation)
-	for _, image := range cocoAnnotations.Images {
-		imageMap[image.ID] = image
-		vocAnnotation := VOCAnnotation{
-			Folder:   "",
-			Filename: image.FileName,
-			Path:     image.FileName,
-			Source: VOCDataSource{
-				Database:   "datasetgo.smslit.cn",
-				Image:      "",
-				Annotation: "",
-			},
-			Size: VOCImageSize{
-				Width:  image.Width,
-				Height: image.Height,
-				Depth:  3,
-			},
-			Segmented: 0,
-			Object:    make([]VOCAnnotationItem, 0),
+	for number, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+		case 2:
+			if fields[1] == "-1" {
+				continue
+			}
+		default:
+			return fmt.Errorf("%v line %v is not an image name with an optional flag", fsPath(fsys, name), number+1)
 		}
-		annotationMap[image.ID] = vocAnnotation
+		*imageSet = append(*imageSet, fields[0])
 	}
+	return nil
+}
 
-	// generate the category map with ID
-	categoryMap := make(map[int]COCOCategory)
-	for _, category := range cocoAnnotations.Categories {
-		categoryMap[category.ID] = category
-	}
+// vocDataset is where the parts of a voc dataset are in its file system
+type vocDataset struct {
+	// the directory of the xml files
+	annotationDir string
 
-	// make the voc annotation data from coco annotations data
-	for _, annotationItem := range cocoAnnotations.Annotations {
-		// check VOVAnnotation data of the image if exists
-		vocAnnotation, ok := annotationMap[annotationItem.ImageID]
-		if !ok {
-			return fmt.Errorf("the image with ID[%v] does not exist(annotation with ID[%v])", annotationItem.ImageID, annotationItem.ID)
-		}
+	// the directory the image file names are relative to
+	imageDir string
 
-		category, ok := categoryMap[annotationItem.CategoryID]
-		if !ok {
-			return fmt.Errorf("the category with ID[%v] does not exist(annotation with ID[%v])", annotationItem.CategoryID, annotationItem.ID)
+	// the image set list to read, all the xml files if empty
+	imageSet string
+}
+
+// resolveVOCDataset finds the parts of the voc dataset at the name, which is
+// either a directory of xml files, a directory like VOC2012 with
+// Annotations/, JPEGImages/ and ImageSets/Main/, a VOCdevkit directory
+// holding one of them, or an image set list in ImageSets/Main/ to read only
+// its images
+func resolveVOCDataset(fsys fs.FS, name string) (vocDataset, error) {
+	if !isDir(fsys, name) {
+		if strings.ToLower(path.Ext(name)) != ".txt" {
+			return vocDataset{}, fmt.Errorf("%v is neither a directory nor an image set list", fsPath(fsys, name))
 		}
-		// convert the coco annotation data to voc annotation data
-		vocAnnotationItem := VOCAnnotationItem{
-			Name:      category.Name,
-			Pose:      Unspecified,
-			Truncated: 0,
-			Difficult: 0,
-			Occluded:  0,
-			Bndbox: VOCBndbox{
-				Xmin: int(annotationItem.BBox[0]),
-				Ymin: int(annotationItem.BBox[1]),
-				Xmax: int(annotationItem.BBox[0] + annotationItem.BBox[2]),
-				Ymax: int(annotationItem.BBox[1] + annotationItem.BBox[3]),
-			},
+		// the image set lists are in ImageSets/Main of the dataset
+		root := path.Dir(path.Dir(path.Dir(name)))
+		if !isDir(fsys, path.Join(root, "Annotations")) {
+			return vocDataset{}, fmt.Errorf("the image set %v is not in ImageSets/Main next to Annotations", fsPath(fsys, name))
+		}
+		dataset := vocDevkitDataset(fsys, root)
+		dataset.imageSet = name
+		return dataset, nil
+	}
+
+	if isDir(fsys, path.Join(name, "Annotations")) {
+		return vocDevkitDataset(fsys, name), nil
+	}
+
+	years := vocDevkitYears(fsys, name)
+	switch len(years) {
+	case 0:
+		return vocDataset{annotationDir: name, imageDir: name}, nil
+	case 1:
+		return vocDevkitDataset(fsys, path.Join(name, years[0])), nil
+	default:
+		return vocDataset{}, fmt.Errorf("the devkit %v holds %v, please choose one of them", fsPath(fsys, name), strings.Join(years, ", "))
+	}
+}
+
+// vocDevkitDataset returns the parts of the voc dataset in the root like
+// VOC2012, the images are in JPEGImages/ or next to Annotations/
+func vocDevkitDataset(fsys fs.FS, root string) vocDataset {
+	imageDir := path.Join(root, "JPEGImages")
+	if !isDir(fsys, imageDir) {
+		imageDir = root
+	}
+	return vocDataset{annotationDir: path.Join(root, "Annotations"), imageDir: imageDir}
+}
+
+// vocDevkitYears returns the subdirectories of the VOCdevkit directory which
+// hold Annotations/, such as VOC2007 and VOC2012
+func vocDevkitYears(fsys fs.FS, dir string) []string {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil
+	}
+
+	var years []string
+	for _, entry := range entries {
+		if entry.IsDir() && isDir(fsys, path.Join(dir, entry.Name(), "Annotations")) {
+			years = append(years, entry.Name())
 		}
-		vocAnnotation.Object = append(vocAnnotation.Object, vocAnnotationItem)
-		annotationMap[annotationItem.ImageID] = vocAnnotation
 	}
+	return years
+}
+
+// readVOCDataset reads the annotations of the image set of the voc dataset,
+// or all the xml files in its annotation directory
+func readVOCDataset(annotations *VOCAnnotations, fsys fs.FS, dataset vocDataset) error {
+	if dataset.imageSet == "" {
+		return ReadVOCAnnotationsFS(annotations, fsys, dataset.annotationDir)
+	}
+
+	var imageSet []string
+	if err := readVOCImageSetFS(&imageSet, fsys, dataset.imageSet); err != nil {
+		return fmt.Errorf("%v reading... %w", fsPath(fsys, dataset.imageSet), err)
+	}
+	names := make([]string, len(imageSet))
+	for index, imageName := range imageSet {
+		names[index] = path.Join(dataset.annotationDir, imageName+".xml")
+	}
+	return readVOCAnnotationFiles(annotations, fsys, names)
+}
+
+// DecodeVOCAnnotations decodes the voc annotations data into the dataset
+func DecodeVOCAnnotations(dataset *Dataset, annotations *VOCAnnotations) error {
+	*dataset = Dataset{
+		Categories:  make([]DatasetCategory, 0),
+		Images:      make([]DatasetImage, 0, len(*annotations)),
+		Annotations: make([]DatasetAnnotation, 0),
+		Metadata:    make(map[string]string),
+	}
+
+	for _, vocAnnotation := range *annotations {
+		if dataset.Metadata["database"] == "" && vocAnnotation.Source.Database != "" {
+			source := map[string]string{
+				"database":   vocAnnotation.Source.Database,
+				"annotation": vocAnnotation.Source.Annotation,
+				"image":      vocAnnotation.Source.Image,
+			}
+			for key, value := range source {
+				if value != "" {
+					dataset.Metadata[key] = value
+				}
+			}
+		}
 
-	for _, annotation := range annotationMap {
-		*annotations = append(*annotations, annotation)
+		attributes := make(map[string]string)
+		if vocAnnotation.Folder != "" {
+			attributes["folder"] = vocAnnotation.Folder
+		}
+		if vocAnnotation.Path != "" && vocAnnotation.Path != vocAnnotation.Filename {
+			attributes["path"] = vocAnnotation.Path
+		}
+		imageID := dataset.appendImage(DatasetImage{
+			FileName:   vocAnnotation.Filename,
+			Width:      vocAnnotation.Size.Width,
+			Height:     vocAnnotation.Size.Height,
+			Depth:      vocAnnotation.Size.Depth,
+			Attributes: attributes,
+		})
+
+		for _, obj := range vocAnnotation.Object {
+			boxWidth := float64(obj.Bndbox.Xmax - obj.Bndbox.Xmin)
+			boxHeight := float64(obj.Bndbox.Ymax - obj.Bndbox.Ymin)
+			objAttributes := map[string]string{
+				"truncated": strconv.Itoa(obj.Truncated),
+				"difficult": strconv.Itoa(obj.Difficult),
+				"occluded":  strconv.Itoa(obj.Occluded),
+			}
+			if obj.Pose != "" {
+				objAttributes["pose"] = string(obj.Pose)
+			}
+			dataset.appendAnnotation(DatasetAnnotation{
+				ImageID:    imageID,
+				CategoryID: dataset.AddCategory(obj.Name),
+				BBox: BoundingBox{
+					X:      float64(obj.Bndbox.Xmin),
+					Y:      float64(obj.Bndbox.Ymin),
+					Width:  boxWidth,
+					Height: boxHeight,
+				},
+				Area:         boxWidth * boxHeight,
+				Segmentation: make([]Polygon, 0),
+				Attributes:   objAttributes,
+				// the flags are numbers
+				JSONAttributes: map[string]bool{"truncated": true, "difficult": true, "occluded": true},
+			})
+		}
 	}
 
 	return nil
 }
 
-func ReadVOCAnnotationsFromCreateMLFile(annotations *VOCAnnotations, path string) error {
-	var createMLAnnotations CreateMLAnnotations
-	if err := ReadCreateMLAnnotationsFromFile(&createMLAnnotations, path); err != nil {
-		return err
+// EncodeVOCAnnotations encodes the dataset into the voc annotations data
+func EncodeVOCAnnotations(annotations *VOCAnnotations, dataset *Dataset) error {
+	database := dataset.Metadata["database"]
+	if database == "" {
+		database = "datasetgo.smslit.cn"
 	}
 
-	for _, createMLAnnotation := range createMLAnnotations {
-		imagePath := filepath.Join(filepath.Dir(path), createMLAnnotation.Image)
-		imageFile, err := os.Open(imagePath)
-		if err != nil {
-			return fmt.Errorf("image [%v] opening... %v", imagePath, err.Error())
+	categoryMap := dataset.CategoryMap()
+	annotationMap := dataset.AnnotationsByImage()
+
+	imageMap := dataset.ImageMap()
+	for _, annotation := range dataset.Annotations {
+		if _, ok := imageMap[annotation.ImageID]; !ok {
+			return &DanglingReferenceError{Kind: ReferenceImage, ID: annotation.ImageID, AnnotationID: annotation.ID}
 		}
-		defer imageFile.Close()
-		imageConfig, _, err := image.DecodeConfig(imageFile)
-		if err != nil {
-			return fmt.Errorf("image [%v] reading... %v", imagePath, err.Error())
+	}
+
+	*annotations = make(VOCAnnotations, 0, len(dataset.Images))
+	for _, image := range dataset.Images {
+		depth := image.Depth
+		if depth == 0 {
+			depth = 3
+		}
+		path := image.Attributes["path"]
+		if path == "" {
+			path = image.FileName
 		}
 		vocAnnotation := VOCAnnotation{
-			Folder:   "",
-			Filename: createMLAnnotation.Image,
-			Path:     createMLAnnotation.Image,
+			Folder:   image.Attributes["folder"],
+			Filename: image.FileName,
+			Path:     path,
 			Source: VOCDataSource{
-				Database:   "datasetgo.smslit.cn",
-				Image:      "",
-				Annotation: "",
+				Database:   database,
+				Image:      dataset.Metadata["image"],
+				Annotation: dataset.Metadata["annotation"],
 			},
 			Size: VOCImageSize{
-				Width:  imageConfig.Width,
-				Height: imageConfig.Height,
-				Depth:  3,
+				Width:  image.Width,
+				Height: image.Height,
+				Depth:  depth,
 			},
 			Segmented: 0,
 			Object:    make([]VOCAnnotationItem, 0),
 		}
 
-		for _, createAnnotationItem := range createMLAnnotation.Annotations {
+		for _, annotation := range annotationMap[image.ID] {
+			category, ok := categoryMap[annotation.CategoryID]
+			if !ok {
+				return &DanglingReferenceError{Kind: ReferenceCategory, ID: annotation.CategoryID, AnnotationID: annotation.ID}
+			}
+
+			pose := VOCPose(annotation.Attributes["pose"])
+			if pose == "" {
+				pose = Unspecified
+			}
+			truncated := vocFlag(annotation.Attributes["truncated"])
+			difficult := vocFlag(annotation.Attributes["difficult"])
+			occluded := vocFlag(annotation.Attributes["occluded"])
 			vocAnnotationItem := VOCAnnotationItem{
-				Name:      createAnnotationItem.Label,
-				Pose:      Unspecified,
-				Truncated: 0,
-				Difficult: 0,
-				Occluded:  0,
+				Name:      category.Name,
+				Pose:      pose,
+				Truncated: truncated,
+				Difficult: difficult,
+				Occluded:  occluded,
 				Bndbox: VOCBndbox{
-					Xmin: int(createAnnotationItem.Coordinates.X),
-					Ymin: int(createAnnotationItem.Coordinates.Y),
-					Xmax: int(createAnnotationItem.Coordinates.X + createAnnotationItem.Coordinates.Width),
-					Ymax: int(createAnnotationItem.Coordinates.Y + createAnnotationItem.Coordinates.Height),
+					Xmin: int(math.Round(annotation.BBox.X)),
+					Ymin: int(math.Round(annotation.BBox.Y)),
+					Xmax: int(math.Round(annotation.BBox.X + annotation.BBox.Width)),
+					Ymax: int(math.Round(annotation.BBox.Y + annotation.BBox.Height)),
 				},
 			}
 			vocAnnotation.Object = append(vocAnnotation.Object, vocAnnotationItem)
@@ -246,12 +418,106 @@ func ReadVOCAnnotationsFromCreateMLFile(annotations *VOCAnnotations, path string
 	return nil
 }
 
+// vocFlag parses the flag attribute of an object, either a number or a
+// boolean such as the true of a coco attribute, 0 if neither
+func vocFlag(value string) int {
+	if flag, err := strconv.Atoi(value); err == nil {
+		return flag
+	}
+	if flag, err := strconv.ParseBool(value); err == nil && flag {
+		return 1
+	}
+	return 0
+}
+
+// ReadDatasetFromPascalVOCDir reads the dataset from the directory of voc
+// xml files, the VOC2012 like directory or the VOCdevkit holding it, or only
+// the images of the image set list like VOC2012/ImageSets/Main/train.txt
+func ReadDatasetFromPascalVOCDir(dataset *Dataset, path string) error {
+	fsys, name := vocPathFS(path)
+	if err := ReadDatasetFromPascalVOCFS(dataset, fsys, name); err != nil {
+		return err
+	}
+
+	dataset.ImageDir = fsys.path(dataset.ImageDir)
+	dataset.ImageFS = nil
+	return nil
+}
+
+// vocPathFS is like osPathFS but the file system of an image set list is
+// rooted above its ImageSets/Main to reach the Annotations/ of the dataset
+func vocPathFS(path string) (osDirFS, string) {
+	fsys, name := osPathFS(path)
+	if name == "." || strings.ToLower(filepath.Ext(path)) != ".txt" {
+		return fsys, name
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fsys, name
+	}
+	root := filepath.Dir(filepath.Dir(filepath.Dir(absPath)))
+	relPath, err := filepath.Rel(root, absPath)
+	if err != nil {
+		return fsys, name
+	}
+	return osDirFS(root), filepath.ToSlash(relPath)
+}
+
+// ReadDatasetFromPascalVOCFS is like ReadDatasetFromPascalVOCDir but reads
+// the dataset at the name of the file system
+func ReadDatasetFromPascalVOCFS(dataset *Dataset, fsys fs.FS, name string) error {
+	layout, err := resolveVOCDataset(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	var annotations VOCAnnotations
+	if err := readVOCDataset(&annotations, fsys, layout); err != nil {
+		return err
+	}
+
+	if err := DecodeVOCAnnotations(dataset, &annotations); err != nil {
+		return err
+	}
+	dataset.ImageDir = layout.imageDir
+	dataset.ImageFS = fsys
+	return nil
+}
+
+// WriteDatasetToPascalVOCDir writes the dataset to the directory in the
+// layout of VOC2012, the xml files to Annotations/ and the image set lists of
+// all the images to ImageSets/Main/trainval.txt and the class lists like
+// car_trainval.txt
+func WriteDatasetToPascalVOCDir(dataset *Dataset, path string) error {
+	if err := writeVOCAnnotationsDir(dataset, filepath.Join(path, "Annotations")); err != nil {
+		return err
+	}
+	return WriteVOCImageSets(dataset, "trainval", filepath.Join(path, "ImageSets", "Main"))
+}
+
+// writeVOCAnnotationsDir writes the dataset to the directory as voc xml files
+func writeVOCAnnotationsDir(dataset *Dataset, path string) error {
+	var annotations VOCAnnotations
+	if err := EncodeVOCAnnotations(&annotations, dataset); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	return WriteVOCAnnotationsToFile(&annotations, path)
+}
+
 func WriteVOCAnnotationsToFile(annotations *VOCAnnotations, path string) error {
 	for _, annotation := range *annotations {
 		imageName := annotation.Filename
 		imageExt := filepath.Ext(imageName)
 		xmlName := strings.TrimSuffix(imageName, imageExt) + ".xml"
-		xmlPath := filepath.Join(path, xmlName)
+		xmlPath := filepath.Join(path, filepath.FromSlash(xmlName))
+		if err := os.MkdirAll(filepath.Dir(xmlPath), os.ModePerm); err != nil {
+			return err
+		}
 		if annotationBytes, err := xml.MarshalIndent(annotation, "", "    "); err != nil {
 			return err
 		} else {
@@ -262,3 +528,124 @@ func WriteVOCAnnotationsToFile(annotations *VOCAnnotations, path string) error {
 	}
 	return nil
 }
+
+// vocWriter writes voc datasets, the splits are listed in ImageSets/Main
+type vocWriter struct{}
+
+func (vocWriter) WriteDataset(dataset *Dataset, path string) error {
+	return WriteDatasetToPascalVOCDir(dataset, path)
+}
+
+func (vocWriter) ImageDir(path string) string {
+	return filepath.Join(path, "JPEGImages")
+}
+
+// WriteDatasetLayout writes the dataset like WriteDataset, the folder of the
+// images is the directory name like VOC2012
+func (vocWriter) WriteDatasetLayout(dataset *Dataset, path string) error {
+	placed := *dataset
+	placed.Images = make([]DatasetImage, len(dataset.Images))
+	for index, image := range dataset.Images {
+		attributes := make(map[string]string, len(image.Attributes)+1)
+		for key, value := range image.Attributes {
+			attributes[key] = value
+		}
+		attributes["folder"] = filepath.Base(path)
+		image.Attributes = attributes
+		placed.Images[index] = image
+	}
+	return WriteDatasetToPascalVOCDir(&placed, path)
+}
+
+// WriteDatasetSplits writes the xml files of all the splits to Annotations/
+// and lists each split in ImageSets/Main, train and val are listed together
+// in trainval as well unless a split has that name
+func (vocWriter) WriteDatasetSplits(splits []DatasetSplit, path string) error {
+	mainDir := filepath.Join(path, "ImageSets", "Main")
+	named := make(map[string]*Dataset, len(splits))
+	for _, split := range splits {
+		if err := writeVOCAnnotationsDir(split.Dataset, filepath.Join(path, "Annotations")); err != nil {
+			return err
+		}
+		if err := WriteVOCImageSets(split.Dataset, split.Name, mainDir); err != nil {
+			return err
+		}
+		named[split.Name] = split.Dataset
+	}
+
+	train, val := named["train"], named["val"]
+	if train == nil || val == nil || named["trainval"] != nil {
+		return nil
+	}
+	trainval := *train
+	trainval.Images = append(append([]DatasetImage{}, train.Images...), val.Images...)
+	trainval.Annotations = append(append([]DatasetAnnotation{}, train.Annotations...), val.Annotations...)
+	return WriteVOCImageSets(&trainval, "trainval", mainDir)
+}
+
+// WriteVOCImageSets writes the image set list of the images of the dataset
+// like train.txt to the directory, and the list of each class like
+// car_train.txt where the images are flagged 1 with the class, 0 with only
+// its difficult objects and -1 without it
+func WriteVOCImageSets(dataset *Dataset, name string, dir string) error {
+	imageSet := make([]string, len(dataset.Images))
+	for index, image := range dataset.Images {
+		imageSet[index] = vocImageSetName(&image)
+	}
+	if err := WriteVOCImageSetToFile(imageSet, filepath.Join(dir, name+".txt")); err != nil {
+		return err
+	}
+
+	// the flags of the images by category ID and image ID
+	flags := make(map[int]map[int]int, len(dataset.Categories))
+	for _, annotation := range dataset.Annotations {
+		imageFlags := flags[annotation.CategoryID]
+		if imageFlags == nil {
+			imageFlags = make(map[int]int)
+			flags[annotation.CategoryID] = imageFlags
+		}
+		flag := 1
+		if vocFlag(annotation.Attributes["difficult"]) != 0 {
+			flag = 0
+		}
+		if current, ok := imageFlags[annotation.ImageID]; !ok || current < flag {
+			imageFlags[annotation.ImageID] = flag
+		}
+	}
+
+	for _, category := range dataset.Categories {
+		classSet := make([]string, len(dataset.Images))
+		for index, image := range dataset.Images {
+			flag, ok := flags[category.ID][image.ID]
+			if !ok {
+				flag = -1
+			}
+			classSet[index] = fmt.Sprintf("%v %2d", imageSet[index], flag)
+		}
+		className := strings.NewReplacer("/", "_", "\\", "_", " ", "_").Replace(category.Name)
+		if err := WriteVOCImageSetToFile(classSet, filepath.Join(dir, className+"_"+name+".txt")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vocImageSetName returns the name of the image in the image set lists, its
+// file name without extension
+func vocImageSetName(image *DatasetImage) string {
+	return strings.TrimSuffix(image.FileName, filepath.Ext(image.FileName))
+}
+
+// WriteVOCImageSetToFile writes the image set list, one image name without
+// extension per line
+func WriteVOCImageSetToFile(imageSet []string, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	content := strings.Join(imageSet, "\n")
+	if len(imageSet) > 0 {
+		content += "\n"
+	}
+	return ioutil.WriteFile(path, []byte(content), 0666)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVOCDevkitRoundTrip(t *testing.T) {
+	var source Dataset
+	if err := ReadDatasetFromPascalVOCDir(&source, filepath.Join("testdata", "voc")); err != nil {
+		t.Fatal(err)
+	}
+	format, err := LookupFormat("voc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devkit := filepath.Join(t.TempDir(), "VOCdevkit")
+	root := filepath.Join(devkit, "VOC2012")
+	if err := WriteDatasetWithImages(format, &source, root, ImagesCopy); err != nil {
+		t.Fatal(err)
+	}
+
+	// the car of a.jpg is difficult and b.jpg has none
+	classSet, err := ioutil.ReadFile(filepath.Join(root, "ImageSets", "Main", "car_trainval.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(classSet) != "a  0\nb -1\n" {
+		t.Errorf("car_trainval.txt = %q, want a flagged 0 and b flagged -1", classSet)
+	}
+
+	var dataset Dataset
+	if err := ReadDatasetFromPascalVOCDir(&dataset, devkit); err != nil {
+		t.Fatal(err)
+	}
+	if len(dataset.Images) != len(source.Images) || len(dataset.Annotations) != len(source.Annotations) {
+		t.Errorf("read %v images and %v annotations, want %v and %v", len(dataset.Images), len(dataset.Annotations), len(source.Images), len(source.Annotations))
+	}
+	if dataset.ImageDir != filepath.Join(root, "JPEGImages") {
+		t.Errorf("image dir = %v, want the JPEGImages of %v", dataset.ImageDir, root)
+	}
+
+	imageSetPath := filepath.Join(root, "ImageSets", "Main", "car_trainval.txt")
+	if detected, err := DetectFormat(imageSetPath); err != nil || detected.Name != "voc" {
+		t.Errorf("detected %v, %v for the image set, want voc", detected, err)
+	}
+	var imageSet Dataset
+	if err := ReadDatasetFromPascalVOCDir(&imageSet, imageSetPath); err != nil {
+		t.Fatal(err)
+	}
+	if len(imageSet.Images) != 1 || imageSet.Images[0].FileName != "a.jpg" {
+		t.Errorf("images of car_trainval.txt = %+v, want a.jpg", imageSet.Images)
+	}
+}
+
+func TestVOCDevkitWithSeveralYears(t *testing.T) {
+	xml := []byte("<annotation><filename>a.jpg</filename></annotation>")
+	fsys := fstest.MapFS{
+		"VOCdevkit/VOC2007/Annotations/a.xml": &fstest.MapFile{Data: xml},
+		"VOCdevkit/VOC2012/Annotations/a.xml": &fstest.MapFile{Data: xml},
+	}
+
+	if !detectPascalVOCDir(fsys, "VOCdevkit") {
+		t.Error("the devkit is not detected as voc")
+	}
+	var dataset Dataset
+	err := ReadDatasetFromPascalVOCFS(&dataset, fsys, "VOCdevkit")
+	if err == nil || !strings.Contains(err.Error(), "VOC2007, VOC2012") {
+		t.Errorf("error = %v, want the years to choose from", err)
+	}
+}
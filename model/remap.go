@@ -0,0 +1,306 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the ways to treat the categories neither renamed nor dropped
+const (
+	UnmappedKeep = "keep"
+	UnmappedDrop = "drop"
+)
+
+// RemapCategory is a category of the remapped dataset
+type RemapCategory struct {
+	Name          string `yaml:"name"`
+	ID            int    `yaml:"id"`
+	SuperCategory string `yaml:"supercategory"`
+}
+
+// CategoryRemap renames, merges and drops the categories of a dataset, read
+// from a yaml or json file such as
+//
+//	rename: {Car: car, automobile: car}
+//	drop: [bicycle]
+//	unmapped: keep
+//	ignore_case: true
+//	categories:
+//	  - {name: car, id: 3, supercategory: vehicle}
+//
+// or a flat object of the renames where an empty name drops the category.
+type CategoryRemap struct {
+	// the new names of the source categories, the categories with the same
+	// new name are merged and an empty name drops the category
+	Rename map[string]string `yaml:"rename"`
+
+	// the source categories to drop
+	Drop []string `yaml:"drop"`
+
+	// UnmappedKeep or UnmappedDrop the categories neither renamed nor dropped
+	Unmapped string `yaml:"unmapped"`
+
+	// match the names of the source categories case-insensitively, and merge
+	// the categories whose names differ only in case
+	IgnoreCase bool `yaml:"ignore_case"`
+
+	// the order and IDs of the remapped categories, the other categories
+	// follow in the order they first appear with the next IDs, the categories
+	// keep their IDs if none is listed
+	Categories []RemapCategory `yaml:"categories"`
+
+	// remove the images which have no annotation left after the remap
+	DropEmptyImages bool `yaml:"drop_empty_images"`
+}
+
+// RemapChange is what became of a source category
+type RemapChange struct {
+	Source    string `json:"source" yaml:"source"`
+	SourceID  int    `json:"source_id" yaml:"source_id"`
+	Target    string `json:"target" yaml:"target"`
+	TargetID  int    `json:"target_id" yaml:"target_id"`
+	Instances int    `json:"instances" yaml:"instances"`
+}
+
+type RemapReport struct {
+	// the source categories in their order, the dropped ones have no target
+	Changes            []RemapChange `json:"changes" yaml:"changes"`
+	Categories         []string      `json:"categories" yaml:"categories"`
+	RemovedAnnotations int           `json:"removed_annotations" yaml:"removed_annotations"`
+	RemovedImages      []string      `json:"removed_images" yaml:"removed_images"`
+}
+
+// ReadCategoryRemapFromFile reads the remap from the yaml or json file
+func ReadCategoryRemapFromFile(remap *CategoryRemap, path string) error {
+	remapFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer remapFile.Close()
+
+	if err := DecodeCategoryRemap(remap, remapFile); err != nil {
+		return fmt.Errorf("%v reading... %v", path, err.Error())
+	}
+	return nil
+}
+
+// DecodeCategoryRemap decodes the remap from the yaml or json read from r. A
+// flat object of the source and target names only renames, but an object
+// with any of the keys of CategoryRemap is decoded as the full remap, so the
+// categories named like rename or drop are renamed under the rename key
+func DecodeCategoryRemap(remap *CategoryRemap, r io.Reader) error {
+	remapBytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(remapBytes, &node); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	if len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return errors.New("the remap must be an object")
+	}
+
+	// an object without any remap key is the flat renames
+	flat := true
+	for index := 0; index < len(node.Content[0].Content); index += 2 {
+		switch node.Content[0].Content[index].Value {
+		case "rename", "drop", "unmapped", "ignore_case", "categories", "drop_empty_images":
+			flat = false
+		}
+	}
+
+	*remap = CategoryRemap{}
+	if flat {
+		err = node.Decode(&remap.Rename)
+	} else {
+		// the renames mixed with the remap keys are not silently ignored
+		decoder := yaml.NewDecoder(bytes.NewReader(remapBytes))
+		decoder.KnownFields(true)
+		err = decoder.Decode(remap)
+	}
+	if err != nil {
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		if !flat {
+			message += ", the categories named like the keys of the remap are renamed under rename"
+		}
+		return errors.New(message)
+	}
+	return nil
+}
+
+// RemapDataset renames, merges and drops the categories of the dataset in
+// place, the annotations of the dropped categories are removed. It fails
+// when the remap renames or drops a category the dataset does not have
+func RemapDataset(dataset *Dataset, remap *CategoryRemap) (RemapReport, error) {
+	unmapped := remap.Unmapped
+	if unmapped == "" {
+		unmapped = UnmappedKeep
+	}
+	if unmapped != UnmappedKeep && unmapped != UnmappedDrop {
+		return RemapReport{}, fmt.Errorf("unknown unmapped [%v], %v or %v", remap.Unmapped, UnmappedKeep, UnmappedDrop)
+	}
+
+	key := func(name string) string {
+		if remap.IgnoreCase {
+			return strings.ToLower(name)
+		}
+		return name
+	}
+	renames := make(map[string]string, len(remap.Rename))
+	for source, target := range remap.Rename {
+		renames[key(source)] = target
+	}
+	drops := make(map[string]bool, len(remap.Drop))
+	for _, source := range remap.Drop {
+		drops[key(source)] = true
+	}
+
+	// the renamed and dropped categories must be in the dataset, a
+	// misspelled name would leave its category unchanged
+	sourceNames := make(map[string]bool, len(dataset.Categories))
+	for _, category := range dataset.Categories {
+		sourceNames[key(category.Name)] = true
+	}
+	unknown := make([]string, 0)
+	for source := range remap.Rename {
+		if !sourceNames[key(source)] {
+			unknown = append(unknown, source)
+		}
+	}
+	for _, source := range remap.Drop {
+		if !sourceNames[key(source)] {
+			unknown = append(unknown, source)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return RemapReport{}, fmt.Errorf("the categories [%v] of the remap are not in the dataset", strings.Join(unknown, ", "))
+	}
+
+	// the listed categories come first with their IDs
+	categories := make([]DatasetCategory, 0, len(remap.Categories))
+	usedIDs := make(map[int]bool, len(remap.Categories))
+	for _, category := range remap.Categories {
+		if category.ID == 0 {
+			continue
+		}
+		if usedIDs[category.ID] {
+			return RemapReport{}, fmt.Errorf("the category ID[%v] of the remap is used more than once", category.ID)
+		}
+		usedIDs[category.ID] = true
+	}
+	nextID := 0
+	targetNames := make(map[int]string)
+	addCategory := func(name string, superCategory string, id int) int {
+		if id == 0 {
+			nextID++
+			for usedIDs[nextID] {
+				nextID++
+			}
+			id = nextID
+		}
+		usedIDs[id] = true
+		targetNames[id] = name
+		categories = append(categories, DatasetCategory{
+			ID:            id,
+			Name:          name,
+			SuperCategory: superCategory,
+		})
+		return id
+	}
+	targetIDs := make(map[string]int)
+	for _, category := range remap.Categories {
+		if _, ok := targetIDs[key(category.Name)]; ok {
+			return RemapReport{}, fmt.Errorf("the category [%v] of the remap is listed more than once", category.Name)
+		}
+		targetIDs[key(category.Name)] = addCategory(category.Name, category.SuperCategory, category.ID)
+	}
+
+	report := RemapReport{
+		Changes:       make([]RemapChange, len(dataset.Categories)),
+		RemovedImages: make([]string, 0),
+	}
+	categoryIDs := make(map[int]int, len(dataset.Categories))
+	changeIndexes := make(map[int]int, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		change := RemapChange{
+			Source:   category.Name,
+			SourceID: category.ID,
+		}
+		changeIndexes[category.ID] = index
+
+		target, renamed := renames[key(category.Name)]
+		if !renamed {
+			target = category.Name
+		}
+		_, listed := targetIDs[key(target)]
+		dropped := drops[key(category.Name)] || (renamed && target == "") ||
+			(!renamed && !listed && unmapped == UnmappedDrop)
+		if !dropped {
+			targetID, ok := targetIDs[key(target)]
+			if !ok {
+				// without the listed categories the source IDs are kept
+				id := 0
+				if len(remap.Categories) == 0 && !usedIDs[category.ID] {
+					id = category.ID
+				}
+				targetID = addCategory(target, category.SuperCategory, id)
+				targetIDs[key(target)] = targetID
+			}
+			change.Target = targetNames[targetID]
+			change.TargetID = targetID
+			categoryIDs[category.ID] = targetID
+		}
+		report.Changes[index] = change
+	}
+
+	// the images with annotations before the remap
+	annotatedImages := make(map[int]bool, len(dataset.Images))
+	remappedImages := make(map[int]bool, len(dataset.Images))
+	annotations := make([]DatasetAnnotation, 0, len(dataset.Annotations))
+	for _, annotation := range dataset.Annotations {
+		annotatedImages[annotation.ImageID] = true
+		if index, ok := changeIndexes[annotation.CategoryID]; ok {
+			report.Changes[index].Instances++
+		}
+
+		categoryID, ok := categoryIDs[annotation.CategoryID]
+		if !ok {
+			report.RemovedAnnotations++
+			continue
+		}
+		annotation.CategoryID = categoryID
+		annotations = append(annotations, annotation)
+		remappedImages[annotation.ImageID] = true
+	}
+	dataset.Categories = categories
+	dataset.Annotations = annotations
+
+	if remap.DropEmptyImages {
+		images := make([]DatasetImage, 0, len(dataset.Images))
+		for _, image := range dataset.Images {
+			if annotatedImages[image.ID] && !remappedImages[image.ID] {
+				report.RemovedImages = append(report.RemovedImages, image.FileName)
+				continue
+			}
+			images = append(images, image)
+		}
+		dataset.Images = images
+	}
+
+	report.Categories = make([]string, len(categories))
+	for index, category := range categories {
+		report.Categories[index] = category.Name
+	}
+	return report, nil
+}
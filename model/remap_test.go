@@ -0,0 +1,175 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newRemapTestDataset returns the images a.jpg to d.jpg holding a Car, an
+// automobile, a bicycle and a person
+func newRemapTestDataset() *Dataset {
+	dataset := &Dataset{
+		Categories: []DatasetCategory{
+			{ID: 1, Name: "Car"},
+			{ID: 2, Name: "automobile"},
+			{ID: 3, Name: "bicycle"},
+			{ID: 4, Name: "person"},
+		},
+	}
+	for index, name := range []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg"} {
+		imageID := dataset.AddImage(DatasetImage{FileName: name, Width: 100, Height: 80})
+		dataset.AddAnnotation(DatasetAnnotation{ImageID: imageID, CategoryID: index + 1, BBox: BoundingBox{Width: 10, Height: 10}})
+	}
+	return dataset
+}
+
+func TestRemapDataset(t *testing.T) {
+	tests := []struct {
+		name       string
+		remap      string
+		categories []DatasetCategory
+		// the category IDs of the annotations left
+		annotations []int
+		images      []string
+	}{
+		{
+			name:        "rename",
+			remap:       "{Car: car}",
+			categories:  []DatasetCategory{{ID: 1, Name: "car"}, {ID: 2, Name: "automobile"}, {ID: 3, Name: "bicycle"}, {ID: 4, Name: "person"}},
+			annotations: []int{1, 2, 3, 4},
+		},
+		{
+			name:        "merge",
+			remap:       "rename: {car: car, automobile: car}\nignore_case: true",
+			categories:  []DatasetCategory{{ID: 1, Name: "car"}, {ID: 3, Name: "bicycle"}, {ID: 4, Name: "person"}},
+			annotations: []int{1, 1, 3, 4},
+		},
+		{
+			name:        "drop",
+			remap:       "rename: {automobile: Car}\ndrop: [bicycle]",
+			categories:  []DatasetCategory{{ID: 1, Name: "Car"}, {ID: 4, Name: "person"}},
+			annotations: []int{1, 1, 4},
+		},
+		{
+			name:        "drop by an empty name",
+			remap:       "{bicycle: ''}",
+			categories:  []DatasetCategory{{ID: 1, Name: "Car"}, {ID: 2, Name: "automobile"}, {ID: 4, Name: "person"}},
+			annotations: []int{1, 2, 4},
+		},
+		{
+			name:        "drop the unmapped",
+			remap:       "rename: {automobile: car, Car: car}\nunmapped: drop\ncategories:\n  - {name: person, id: 1}\n  - {name: car, id: 3}",
+			categories:  []DatasetCategory{{ID: 1, Name: "person"}, {ID: 3, Name: "car"}},
+			annotations: []int{3, 3, 1},
+		},
+		{
+			name:        "drop the empty images",
+			remap:       "drop: [bicycle, person]\ndrop_empty_images: true",
+			categories:  []DatasetCategory{{ID: 1, Name: "Car"}, {ID: 2, Name: "automobile"}},
+			annotations: []int{1, 2},
+			images:      []string{"a.jpg", "b.jpg"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var remap CategoryRemap
+			if err := DecodeCategoryRemap(&remap, strings.NewReader(test.remap)); err != nil {
+				t.Fatal(err)
+			}
+			dataset := newRemapTestDataset()
+			report, err := RemapDataset(dataset, &remap)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(dataset.Categories, test.categories) {
+				t.Errorf("categories = %+v, want %+v", dataset.Categories, test.categories)
+			}
+			annotations := make([]int, len(dataset.Annotations))
+			for index, annotation := range dataset.Annotations {
+				annotations[index] = annotation.CategoryID
+			}
+			if !reflect.DeepEqual(annotations, test.annotations) {
+				t.Errorf("the categories of the annotations = %v, want %v", annotations, test.annotations)
+			}
+			if report.RemovedAnnotations != 4-len(test.annotations) {
+				t.Errorf("removed annotations = %v, want %v", report.RemovedAnnotations, 4-len(test.annotations))
+			}
+
+			if test.images == nil {
+				test.images = []string{"a.jpg", "b.jpg", "c.jpg", "d.jpg"}
+			}
+			images := make([]string, len(dataset.Images))
+			for index, image := range dataset.Images {
+				images[index] = image.FileName
+			}
+			if !reflect.DeepEqual(images, test.images) {
+				t.Errorf("images = %v, want %v", images, test.images)
+			}
+		})
+	}
+}
+
+func TestRemapDatasetErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		remap string
+		err   string
+	}{
+		{"unknown rename", "{Car: car, truck: car}", "the categories [truck] of the remap are not in the dataset"},
+		{"unknown drop", "drop: [bike, Person]", "the categories [Person, bike] of the remap are not in the dataset"},
+		{"unknown unmapped", "unmapped: merge", "unknown unmapped [merge], keep or drop"},
+		{"duplicate ID", "categories: [{name: car, id: 1}, {name: person, id: 1}]", "the category ID[1] of the remap is used more than once"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var remap CategoryRemap
+			if err := DecodeCategoryRemap(&remap, strings.NewReader(test.remap)); err != nil {
+				t.Fatal(err)
+			}
+			dataset := newRemapTestDataset()
+			if _, err := RemapDataset(dataset, &remap); err == nil || err.Error() != test.err {
+				t.Errorf("error = %v, want %v", err, test.err)
+			}
+			if len(dataset.Categories) != 4 || len(dataset.Annotations) != 4 {
+				t.Error("the dataset is changed by the failed remap")
+			}
+		})
+	}
+}
+
+func TestDecodeCategoryRemap(t *testing.T) {
+	tests := []struct {
+		name  string
+		remap string
+		want  CategoryRemap
+		err   string
+	}{
+		{"flat", "{Car: car, bicycle: ''}", CategoryRemap{Rename: map[string]string{"Car": "car", "bicycle": ""}}, ""},
+		{"flat json", `{"Car": "car"}`, CategoryRemap{Rename: map[string]string{"Car": "car"}}, ""},
+		{"full", "rename: {Car: car}\ndrop: [bicycle]", CategoryRemap{Rename: map[string]string{"Car": "car"}, Drop: []string{"bicycle"}}, ""},
+		{"category named like a key", "rename: {drop: car}", CategoryRemap{Rename: map[string]string{"drop": "car"}}, ""},
+		{"flat with a key", "{Car: car, drop: car}", CategoryRemap{}, "the categories named like the keys of the remap are renamed under rename"},
+		{"renames beside the keys", "{Car: car, drop: [bicycle]}", CategoryRemap{}, "field Car not found"},
+		{"list", "[Car]", CategoryRemap{}, "the remap must be an object"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var remap CategoryRemap
+			err := DecodeCategoryRemap(&remap, strings.NewReader(test.remap))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(remap, test.want) {
+				t.Errorf("remap = %+v, want %+v", remap, test.want)
+			}
+		})
+	}
+}
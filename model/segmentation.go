@@ -0,0 +1,184 @@
+package model
+
+import (
+	"errors"
+	"math"
+)
+
+// RLEMask is a binary mask run-length encoded in column-major order as COCO
+// does, the counts alternate between runs of zeros and ones starting with zeros
+type RLEMask struct {
+	Height int
+	Width  int
+	Counts []int
+
+	// whether the counts were read as a compressed string, so that they are
+	// written back the same way
+	Compressed bool
+}
+
+// Area returns the number of pixels in the mask
+func (mask *RLEMask) Area() float64 {
+	area := 0
+	for index := 1; index < len(mask.Counts); index += 2 {
+		area += mask.Counts[index]
+	}
+	return float64(area)
+}
+
+// BBox returns the tight bounding box of the pixels in the mask
+func (mask *RLEMask) BBox() BoundingBox {
+	if mask.Height <= 0 {
+		return BoundingBox{}
+	}
+
+	minX, minY := mask.Width, mask.Height
+	maxX, maxY := -1, -1
+	position := 0
+	for index, count := range mask.Counts {
+		if index%2 == 1 && count > 0 {
+			startX, startY := position/mask.Height, position%mask.Height
+			endX, endY := (position+count-1)/mask.Height, (position+count-1)%mask.Height
+			if startX != endX {
+				// the run wraps to the next column, so it covers the full height
+				startY, endY = 0, mask.Height-1
+			}
+			minX = minInt(minX, startX)
+			maxX = maxInt(maxX, endX)
+			minY = minInt(minY, startY)
+			maxY = maxInt(maxY, endY)
+		}
+		position += count
+	}
+
+	if maxX < 0 {
+		return BoundingBox{}
+	}
+	return BoundingBox{
+		X:      float64(minX),
+		Y:      float64(minY),
+		Width:  float64(maxX - minX + 1),
+		Height: float64(maxY - minY + 1),
+	}
+}
+
+// DecodeRLECounts decodes the compressed counts string of the COCO api
+func DecodeRLECounts(compressed string) ([]int, error) {
+	counts := make([]int, 0)
+	for position := 0; position < len(compressed); {
+		value, shift := 0, uint(0)
+		for more := true; more; {
+			if position >= len(compressed) {
+				return nil, errors.New("the compressed rle counts end in the middle of a value")
+			}
+			char := int(compressed[position]) - 48
+			if char < 0 || char > 63 {
+				return nil, errors.New("the compressed rle counts hold an invalid character")
+			}
+			value |= (char & 0x1f) << shift
+			more = char&0x20 != 0
+			position++
+			shift += 5
+			if !more && char&0x10 != 0 {
+				value |= -1 << shift
+			}
+		}
+		if len(counts) > 2 {
+			value += counts[len(counts)-2]
+		}
+		counts = append(counts, value)
+	}
+	return counts, nil
+}
+
+// EncodeRLECounts encodes the counts to the compressed string of the COCO api
+func EncodeRLECounts(counts []int) string {
+	compressed := make([]byte, 0, len(counts)*2)
+	for index, count := range counts {
+		value := count
+		if index > 2 {
+			value -= counts[index-2]
+		}
+		for more := true; more; {
+			char := value & 0x1f
+			value >>= 5
+			if char&0x10 != 0 {
+				more = value != -1
+			} else {
+				more = value != 0
+			}
+			if more {
+				char |= 0x20
+			}
+			compressed = append(compressed, byte(char+48))
+		}
+	}
+	return string(compressed)
+}
+
+// Area returns the area enclosed by the polygon
+func (polygon Polygon) Area() float64 {
+	area := 0.0
+	points := len(polygon) / 2
+	for index := 0; index < points; index++ {
+		next := (index + 1) % points
+		area += polygon[2*index]*polygon[2*next+1] - polygon[2*next]*polygon[2*index+1]
+	}
+	return math.Abs(area) / 2
+}
+
+// PolygonsBBox returns the bounding box of all the polygons
+func PolygonsBBox(polygons []Polygon) BoundingBox {
+	points := make([]float64, 0)
+	for _, polygon := range polygons {
+		points = append(points, polygon...)
+	}
+	if len(points) < 2 {
+		return BoundingBox{}
+	}
+
+	minX, minY, maxX, maxY := polygonExtent(points)
+	return BoundingBox{
+		X:      minX,
+		Y:      minY,
+		Width:  maxX - minX,
+		Height: maxY - minY,
+	}
+}
+
+// SegmentationArea returns the area of the mask or the polygons of the
+// annotation, zero if it has no segmentation
+func (annotation *DatasetAnnotation) SegmentationArea() float64 {
+	if annotation.Mask != nil {
+		return annotation.Mask.Area()
+	}
+
+	area := 0.0
+	for _, polygon := range annotation.Segmentation {
+		area += polygon.Area()
+	}
+	return area
+}
+
+// SegmentationBBox returns the bounding box of the mask or the polygons of
+// the annotation, an empty box if it has no segmentation
+func (annotation *DatasetAnnotation) SegmentationBBox() BoundingBox {
+	if annotation.Mask != nil {
+		return annotation.Mask.BBox()
+	}
+	return PolygonsBBox(annotation.Segmentation)
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
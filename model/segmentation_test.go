@@ -0,0 +1,98 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRLECounts(t *testing.T) {
+	// the strings are the output of rleToString of the COCO api
+	tests := []struct {
+		counts     []int
+		compressed string
+	}{
+		{[]int{0, 4}, "04"},
+		{[]int{272, 2, 374766}, "`82^o];"},
+		{[]int{5, 3, 12, 7, 73}, "53<4m1"},
+		{[]int{8, 4, 88}, "84h2"},
+	}
+	for _, test := range tests {
+		if compressed := EncodeRLECounts(test.counts); compressed != test.compressed {
+			t.Errorf("encoded %v = %q, want %q", test.counts, compressed, test.compressed)
+		}
+		counts, err := DecodeRLECounts(test.compressed)
+		if err != nil {
+			t.Errorf("decoding %q: %v", test.compressed, err)
+		} else if !reflect.DeepEqual(counts, test.counts) {
+			t.Errorf("decoded %q = %v, want %v", test.compressed, counts, test.counts)
+		}
+	}
+
+	for _, compressed := range []string{"0`", "0\x7f"} {
+		if _, err := DecodeRLECounts(compressed); err == nil {
+			t.Errorf("decoded the invalid counts %q", compressed)
+		}
+	}
+}
+
+func TestSegmentationBBoxAndArea(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation DatasetAnnotation
+		bbox       BoundingBox
+		area       float64
+	}{
+		{
+			name:       "none",
+			annotation: DatasetAnnotation{},
+		},
+		{
+			name:       "square",
+			annotation: DatasetAnnotation{Segmentation: []Polygon{{10, 20, 30, 20, 30, 40, 10, 40}}},
+			bbox:       BoundingBox{X: 10, Y: 20, Width: 20, Height: 20},
+			area:       400,
+		},
+		{
+			name: "polygons",
+			annotation: DatasetAnnotation{Segmentation: []Polygon{
+				{0, 0, 4, 0, 0, 3},
+				{10, 10, 12, 10, 12, 15, 10, 15},
+			}},
+			bbox: BoundingBox{X: 0, Y: 0, Width: 12, Height: 15},
+			area: 6 + 10,
+		},
+		{
+			name:       "crowd",
+			annotation: DatasetAnnotation{Mask: &RLEMask{Height: 640, Width: 586, Counts: []int{272, 2, 374766}}},
+			bbox:       BoundingBox{X: 0, Y: 272, Width: 1, Height: 2},
+			area:       2,
+		},
+		{
+			name:       "runs",
+			annotation: DatasetAnnotation{Mask: &RLEMask{Height: 10, Width: 10, Counts: []int{5, 3, 12, 7, 73}}},
+			bbox:       BoundingBox{X: 0, Y: 0, Width: 3, Height: 8},
+			area:       10,
+		},
+		{
+			// the run goes from the bottom of the first column to the top of the second
+			name:       "wrapped run",
+			annotation: DatasetAnnotation{Mask: &RLEMask{Height: 10, Width: 10, Counts: []int{8, 4, 88}}},
+			bbox:       BoundingBox{X: 0, Y: 0, Width: 2, Height: 10},
+			area:       4,
+		},
+		{
+			name:       "empty mask",
+			annotation: DatasetAnnotation{Mask: &RLEMask{Height: 10, Width: 10, Counts: []int{100}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if bbox := test.annotation.SegmentationBBox(); bbox != test.bbox {
+				t.Errorf("bbox = %+v, want %+v", bbox, test.bbox)
+			}
+			if area := test.annotation.SegmentationArea(); area != test.area {
+				t.Errorf("area = %v, want %v", area, test.area)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// DatasetSplit is a named part of a dataset, e.g. train
+type DatasetSplit struct {
+	Name    string
+	Dataset *Dataset
+}
+
+// DatasetSplitWriter is implemented by the writers which store all the splits
+// of a dataset together, like the image set lists of PascalVOC
+type DatasetSplitWriter interface {
+	WriteDatasetSplits(splits []DatasetSplit, path string) error
+}
+
+// SplitOptions controls how the images are partitioned, either Ratios or
+// Counts gives the size of each split in the order of Names
+type SplitOptions struct {
+	Names []string
+
+	// the relative sizes of the splits, they do not need to sum to 1
+	Ratios []float64
+
+	// the absolute number of images in each split, the images left over go
+	// to the first split
+	Counts []int
+
+	// the seed of the shuffle, the same seed always gives the same splits
+	Seed int64
+
+	// balance the share of the instances of every category across the
+	// splits with iterative stratification
+	Stratify bool
+
+	// the images whose file names give the same first submatch, or the same
+	// match if the pattern has no group, are kept in one split
+	GroupPattern *regexp.Regexp
+
+	// the images with the same value of the attribute, e.g. the video_id
+	// field of COCO images, are kept in one split
+	GroupAttribute string
+}
+
+// SplitDataset partitions the images of the dataset randomly into the splits
+func SplitDataset(dataset *Dataset, options SplitOptions) ([]DatasetSplit, error) {
+	counts, err := splitCounts(len(dataset.Images), options)
+	if err != nil {
+		return nil, err
+	}
+
+	random := rand.New(rand.NewSource(options.Seed))
+	if options.Stratify || options.GroupPattern != nil || options.GroupAttribute != "" {
+		return splitDatasetGroups(dataset, options.Names, counts, options, random), nil
+	}
+
+	imageIDs := make([]int, len(dataset.Images))
+	for index, image := range dataset.Images {
+		imageIDs[index] = image.ID
+	}
+	random.Shuffle(len(imageIDs), func(i, j int) {
+		imageIDs[i], imageIDs[j] = imageIDs[j], imageIDs[i]
+	})
+
+	splits := make([]DatasetSplit, len(options.Names))
+	start := 0
+	for index, name := range options.Names {
+		splits[index] = DatasetSplit{
+			Name:    name,
+			Dataset: dataset.Subset(imageIDs[start : start+counts[index]]),
+		}
+		start += counts[index]
+	}
+	return splits, nil
+}
+
+// splitGroup is a set of images which must go to the same split
+type splitGroup struct {
+	imageIDs []int
+
+	// the number of instances of each category in the images
+	instances map[int]int
+}
+
+// groupImages groups the images by the group key of the options, every image
+// is a group of its own when it has no key
+func groupImages(dataset *Dataset, options SplitOptions) []*splitGroup {
+	groups := make([]*splitGroup, 0, len(dataset.Images))
+	groupMap := make(map[string]*splitGroup)
+	imageGroups := make(map[int]*splitGroup, len(dataset.Images))
+
+	for _, image := range dataset.Images {
+		key := ""
+		if options.GroupPattern != nil {
+			if match := options.GroupPattern.FindStringSubmatch(image.FileName); len(match) > 1 {
+				key = match[1]
+			} else if len(match) == 1 {
+				key = match[0]
+			}
+		}
+		if options.GroupAttribute != "" {
+			if value, ok := image.Attributes[options.GroupAttribute]; ok {
+				key += "\x00" + value
+			}
+		}
+
+		group, ok := groupMap[key]
+		if key == "" || !ok {
+			group = &splitGroup{instances: make(map[int]int)}
+			groups = append(groups, group)
+			if key != "" {
+				groupMap[key] = group
+			}
+		}
+		group.imageIDs = append(group.imageIDs, image.ID)
+		imageGroups[image.ID] = group
+	}
+
+	for _, annotation := range dataset.Annotations {
+		if group, ok := imageGroups[annotation.ImageID]; ok {
+			group.instances[annotation.CategoryID]++
+		}
+	}
+	return groups
+}
+
+// splitDatasetGroups assigns whole groups of images to the splits, the
+// sizes of the splits follow the counts as close as the groups allow.
+// With stratification it is the iterative stratification of Sechidis et al.
+// on instance counts: the category with the fewest instances left is
+// distributed first, each group going to the split which still wants the
+// most instances of it
+func splitDatasetGroups(dataset *Dataset, names []string, counts []int, options SplitOptions, random *rand.Rand) []DatasetSplit {
+	groups := groupImages(dataset, options)
+	random.Shuffle(len(groups), func(i, j int) {
+		groups[i], groups[j] = groups[j], groups[i]
+	})
+
+	total := float64(len(dataset.Images))
+	ratios := make([]float64, len(counts))
+	for index, count := range counts {
+		if total > 0 {
+			ratios[index] = float64(count) / total
+		}
+	}
+
+	// the numbers of images and instances each split still wants
+	wantedImages := make([]float64, len(counts))
+	for index, count := range counts {
+		wantedImages[index] = float64(count)
+	}
+	wantedInstances := make([]map[int]float64, len(counts))
+	for index := range wantedInstances {
+		wantedInstances[index] = make(map[int]float64)
+	}
+	if options.Stratify {
+		instanceTotals := make(map[int]int)
+		for _, group := range groups {
+			for categoryID, count := range group.instances {
+				instanceTotals[categoryID] += count
+			}
+		}
+		for categoryID, count := range instanceTotals {
+			for index, ratio := range ratios {
+				wantedInstances[index][categoryID] = ratio * float64(count)
+			}
+		}
+	}
+
+	assignments := make([][]int, len(counts))
+	assign := func(group *splitGroup, index int) {
+		assignments[index] = append(assignments[index], group.imageIDs...)
+		wantedImages[index] -= float64(len(group.imageIDs))
+		for categoryID, count := range group.instances {
+			wantedInstances[index][categoryID] -= float64(count)
+		}
+	}
+
+	// the split wanting the most of the category, then the most images,
+	// ties are broken by the order of the splits
+	bestSplit := func(categoryID int, hasCategory bool) int {
+		best := -1
+		for index := range counts {
+			if ratios[index] == 0 {
+				continue
+			}
+			if best < 0 {
+				best = index
+				continue
+			}
+			if hasCategory {
+				wanted, bestWanted := wantedInstances[index][categoryID], wantedInstances[best][categoryID]
+				if wanted != bestWanted {
+					if wanted > bestWanted {
+						best = index
+					}
+					continue
+				}
+			}
+			if wantedImages[index] > wantedImages[best] {
+				best = index
+			}
+		}
+		if best < 0 {
+			best = 0
+		}
+		return best
+	}
+
+	remaining := groups
+	for options.Stratify && len(remaining) > 0 {
+		// find the category with the fewest instances left
+		instancesLeft := make(map[int]int)
+		for _, group := range remaining {
+			for categoryID, count := range group.instances {
+				instancesLeft[categoryID] += count
+			}
+		}
+		rarest, fewest := 0, -1
+		for categoryID, count := range instancesLeft {
+			if count > 0 && (fewest < 0 || count < fewest || (count == fewest && categoryID < rarest)) {
+				rarest, fewest = categoryID, count
+			}
+		}
+		if fewest < 0 {
+			break
+		}
+
+		next := make([]*splitGroup, 0, len(remaining))
+		for _, group := range remaining {
+			if group.instances[rarest] > 0 {
+				assign(group, bestSplit(rarest, true))
+			} else {
+				next = append(next, group)
+			}
+		}
+		remaining = next
+	}
+
+	// the groups left have no instances to balance
+	for _, group := range remaining {
+		assign(group, bestSplit(0, false))
+	}
+
+	splits := make([]DatasetSplit, len(names))
+	for index, name := range names {
+		splits[index] = DatasetSplit{
+			Name:    name,
+			Dataset: dataset.Subset(assignments[index]),
+		}
+	}
+	return splits
+}
+
+// splitCounts returns the number of images in each split
+func splitCounts(total int, options SplitOptions) ([]int, error) {
+	if len(options.Names) == 0 {
+		return nil, errors.New("at least one split name is required")
+	}
+
+	if len(options.Counts) > 0 {
+		if len(options.Counts) != len(options.Names) {
+			return nil, fmt.Errorf("got %v counts for %v splits", len(options.Counts), len(options.Names))
+		}
+		counts := make([]int, len(options.Counts))
+		sum := 0
+		for index, count := range options.Counts {
+			if count < 0 {
+				return nil, fmt.Errorf("the count of split %v must not be negative", options.Names[index])
+			}
+			counts[index] = count
+			sum += count
+		}
+		if sum > total {
+			return nil, fmt.Errorf("the counts sum to %v but the dataset has %v images", sum, total)
+		}
+		counts[0] += total - sum
+		return counts, nil
+	}
+
+	if len(options.Ratios) != len(options.Names) {
+		return nil, fmt.Errorf("got %v ratios for %v splits", len(options.Ratios), len(options.Names))
+	}
+	ratioSum := 0.0
+	for index, ratio := range options.Ratios {
+		if ratio < 0 || math.IsNaN(ratio) {
+			return nil, fmt.Errorf("the ratio of split %v must not be negative", options.Names[index])
+		}
+		ratioSum += ratio
+	}
+	if ratioSum == 0 {
+		return nil, errors.New("the ratios must not all be zero")
+	}
+
+	// largest remainder method, so that the counts sum to the total
+	counts := make([]int, len(options.Ratios))
+	remainders := make([]int, len(options.Ratios))
+	assigned := 0
+	for index, ratio := range options.Ratios {
+		exact := float64(total) * ratio / ratioSum
+		counts[index] = int(math.Floor(exact))
+		assigned += counts[index]
+		remainders[index] = index
+	}
+	sort.SliceStable(remainders, func(i, j int) bool {
+		exactI := float64(total) * options.Ratios[remainders[i]] / ratioSum
+		exactJ := float64(total) * options.Ratios[remainders[j]] / ratioSum
+		return exactI-math.Floor(exactI) > exactJ-math.Floor(exactJ)
+	})
+	for index := 0; assigned < total; index++ {
+		counts[remainders[index%len(remainders)]]++
+		assigned++
+	}
+	return counts, nil
+}
+
+// WriteDatasetSplits writes the splits in the format under the directory,
+// each split becomes <name><extension> or the directory <name> unless the
+// writer of the format stores the splits together
+func WriteDatasetSplits(format *Format, splits []DatasetSplit, path string) error {
+	if format.Writer == nil {
+		return fmt.Errorf("the format %v can not be written", format.Name)
+	}
+
+	if splitWriter, ok := format.Writer.(DatasetSplitWriter); ok {
+		return splitWriter.WriteDatasetSplits(splits, path)
+	}
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	for _, split := range splits {
+		splitPath := filepath.Join(path, split.Name+format.Extension)
+		if err := format.Writer.WriteDataset(split.Dataset, splitPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DatasetFold is a train/val pair of the k-fold cross-validation
+type DatasetFold struct {
+	// the number of the fold, from 1
+	Index int
+	Train *Dataset
+	Val   *Dataset
+}
+
+// FoldDataset partitions the images into k folds, each fold is the val set
+// of one pair with the other folds as its train set. The names, ratios and
+// counts of the options are ignored, the stratification and grouping apply
+func FoldDataset(dataset *Dataset, folds int, options SplitOptions) ([]DatasetFold, error) {
+	if folds < 2 {
+		return nil, errors.New("at least 2 folds are required")
+	}
+	if folds > len(dataset.Images) {
+		return nil, fmt.Errorf("can not make %v folds of %v images", folds, len(dataset.Images))
+	}
+
+	options.Names = make([]string, folds)
+	options.Ratios = make([]float64, folds)
+	options.Counts = nil
+	for index := range options.Names {
+		options.Names[index] = fmt.Sprintf("fold-%d", index+1)
+		options.Ratios[index] = 1
+	}
+	parts, err := SplitDataset(dataset, options)
+	if err != nil {
+		return nil, err
+	}
+
+	datasetFolds := make([]DatasetFold, folds)
+	for index, part := range parts {
+		trainImageIDs := make([]int, 0, len(dataset.Images)-len(part.Dataset.Images))
+		for other, otherPart := range parts {
+			if other == index {
+				continue
+			}
+			for _, image := range otherPart.Dataset.Images {
+				trainImageIDs = append(trainImageIDs, image.ID)
+			}
+		}
+		datasetFolds[index] = DatasetFold{
+			Index: index + 1,
+			Train: dataset.Subset(trainImageIDs),
+			Val:   part.Dataset,
+		}
+	}
+	return datasetFolds, nil
+}
+
+// FoldManifestFold lists the val images of a fold
+type FoldManifestFold struct {
+	Fold      int      `json:"fold"`
+	ImageIDs  []int    `json:"image_ids"`
+	FileNames []string `json:"file_names"`
+}
+
+// FoldManifest records how the images were assigned to the folds, so that
+// the experiments can be reproduced
+type FoldManifest struct {
+	Source     string             `json:"source"`
+	Folds      int                `json:"folds"`
+	Seed       int64              `json:"seed"`
+	Stratify   bool               `json:"stratify"`
+	GroupRegex string             `json:"group_regex,omitempty"`
+	GroupField string             `json:"group_field,omitempty"`
+	ValImages  []FoldManifestFold `json:"val_images"`
+}
+
+// NewFoldManifest describes the folds made from the source with the options
+func NewFoldManifest(source string, folds []DatasetFold, options SplitOptions) FoldManifest {
+	manifest := FoldManifest{
+		Source:     source,
+		Folds:      len(folds),
+		Seed:       options.Seed,
+		Stratify:   options.Stratify,
+		GroupField: options.GroupAttribute,
+		ValImages:  make([]FoldManifestFold, len(folds)),
+	}
+	if options.GroupPattern != nil {
+		manifest.GroupRegex = options.GroupPattern.String()
+	}
+
+	for index, fold := range folds {
+		manifestFold := FoldManifestFold{
+			Fold:      fold.Index,
+			ImageIDs:  make([]int, len(fold.Val.Images)),
+			FileNames: make([]string, len(fold.Val.Images)),
+		}
+		for i, image := range fold.Val.Images {
+			manifestFold.ImageIDs[i] = image.ID
+			manifestFold.FileNames[i] = image.FileName
+		}
+		manifest.ValImages[index] = manifestFold
+	}
+	return manifest
+}
+
+// WriteFoldManifestToFile writes the manifest as a json file
+func WriteFoldManifestToFile(manifest *FoldManifest, path string) error {
+	manifestBytes, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, manifestBytes, 0666)
+}
@@ -0,0 +1,450 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// newImagesTestDataset returns the images 0.jpg to <n-1>.jpg holding a car each
+func newImagesTestDataset(n int) *Dataset {
+	dataset := &Dataset{Categories: []DatasetCategory{{ID: 1, Name: "car"}}}
+	for index := 0; index < n; index++ {
+		imageID := dataset.AddImage(DatasetImage{FileName: fmt.Sprintf("%d.jpg", index), Width: 100, Height: 100})
+		dataset.AddAnnotation(DatasetAnnotation{ImageID: imageID, CategoryID: 1, BBox: BoundingBox{Width: 10, Height: 10}})
+	}
+	return dataset
+}
+
+// newSplitTestDataset returns a dataset of the images seq<g>_<i>.jpg in
+// groups of size, every image holds a car and the first rare images hold a
+// person too
+func newSplitTestDataset(groups int, size int, rare int) *Dataset {
+	dataset := &Dataset{Categories: []DatasetCategory{{ID: 1, Name: "car"}, {ID: 2, Name: "person"}}}
+	for group := 0; group < groups; group++ {
+		for index := 0; index < size; index++ {
+			imageID := dataset.AddImage(DatasetImage{
+				FileName:   fmt.Sprintf("seq%d_%d.jpg", group, index),
+				Width:      100,
+				Height:     100,
+				Attributes: map[string]string{"video_id": strconv.Itoa(group)},
+			})
+			dataset.AddAnnotation(DatasetAnnotation{ImageID: imageID, CategoryID: 1, BBox: BoundingBox{Width: 10, Height: 10}})
+			if imageID <= rare {
+				dataset.AddAnnotation(DatasetAnnotation{ImageID: imageID, CategoryID: 2, BBox: BoundingBox{Width: 10, Height: 10}})
+			}
+		}
+	}
+	return dataset
+}
+
+func splitImageIDs(splits []DatasetSplit) [][]int {
+	imageIDs := make([][]int, len(splits))
+	for index, split := range splits {
+		for _, image := range split.Dataset.Images {
+			imageIDs[index] = append(imageIDs[index], image.ID)
+		}
+	}
+	return imageIDs
+}
+
+func TestSplitCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		options SplitOptions
+		counts  []int
+		err     string
+	}{
+		{
+			name:    "ratios",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val", "test"}, Ratios: []float64{0.8, 0.1, 0.1}},
+			counts:  []int{8, 1, 1},
+		},
+		{
+			// the ratios are relative, the equal remainders go to the first split
+			name:    "ratios not summing to 1",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Ratios: []float64{3, 1}},
+			counts:  []int{8, 2},
+		},
+		{
+			name:    "largest remainder",
+			total:   7,
+			options: SplitOptions{Names: []string{"train", "val", "test"}, Ratios: []float64{0.5, 0.3, 0.2}},
+			counts:  []int{4, 2, 1},
+		},
+		{
+			name:    "equal thirds",
+			total:   10,
+			options: SplitOptions{Names: []string{"a", "b", "c"}, Ratios: []float64{1, 1, 1}},
+			counts:  []int{4, 3, 3},
+		},
+		{
+			name:    "zero ratio",
+			total:   5,
+			options: SplitOptions{Names: []string{"train", "val"}, Ratios: []float64{1, 0}},
+			counts:  []int{5, 0},
+		},
+		{
+			// the images left over go to the first split
+			name:    "counts",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val", "test"}, Counts: []int{3, 2, 1}},
+			counts:  []int{7, 2, 1},
+		},
+		{
+			name:    "counts larger than the dataset",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Counts: []int{8, 3}},
+			err:     "the counts sum to 11 but the dataset has 10 images",
+		},
+		{
+			name:    "negative count",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Counts: []int{8, -1}},
+			err:     "the count of split val must not be negative",
+		},
+		{
+			name:    "counts of other splits",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Counts: []int{8}},
+			err:     "got 1 counts for 2 splits",
+		},
+		{
+			name:    "ratios of other splits",
+			total:   10,
+			options: SplitOptions{Names: []string{"train"}, Ratios: []float64{0.8, 0.2}},
+			err:     "got 2 ratios for 1 splits",
+		},
+		{
+			name:    "negative ratio",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Ratios: []float64{1, -0.5}},
+			err:     "the ratio of split val must not be negative",
+		},
+		{
+			name:    "zero ratios",
+			total:   10,
+			options: SplitOptions{Names: []string{"train", "val"}, Ratios: []float64{0, 0}},
+			err:     "the ratios must not all be zero",
+		},
+		{
+			name:  "no split",
+			total: 10,
+			err:   "at least one split name is required",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counts, err := splitCounts(test.total, test.options)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(counts, test.counts) {
+				t.Errorf("counts = %v, want %v", counts, test.counts)
+			}
+		})
+	}
+}
+
+func TestSplitDataset(t *testing.T) {
+	dataset := newImagesTestDataset(20)
+	options := SplitOptions{Names: []string{"train", "val", "test"}, Ratios: []float64{0.7, 0.2, 0.1}, Seed: 42}
+	splits, err := SplitDataset(dataset, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every image is in one split with its annotation
+	seen := make(map[int]string)
+	for index, split := range splits {
+		if split.Name != options.Names[index] {
+			t.Errorf("split %v is named %v, want %v", index, split.Name, options.Names[index])
+		}
+		if want := []int{14, 4, 2}[index]; len(split.Dataset.Images) != want {
+			t.Errorf("%v holds %v images, want %v", split.Name, len(split.Dataset.Images), want)
+		}
+		for _, image := range split.Dataset.Images {
+			if name, ok := seen[image.ID]; ok {
+				t.Errorf("the image %v is in both %v and %v", image.FileName, name, split.Name)
+			}
+			seen[image.ID] = split.Name
+		}
+		for _, annotation := range split.Dataset.Annotations {
+			if seen[annotation.ImageID] != split.Name {
+				t.Errorf("the annotation of image ID[%v] is in %v, not with its image", annotation.ImageID, split.Name)
+			}
+		}
+	}
+	if len(seen) != len(dataset.Images) {
+		t.Errorf("the splits hold %v images, want %v", len(seen), len(dataset.Images))
+	}
+
+	again, err := SplitDataset(newImagesTestDataset(20), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(splitImageIDs(again), splitImageIDs(splits)) {
+		t.Error("the splits differ for the same seed")
+	}
+	options.Seed = 43
+	other, err := SplitDataset(newImagesTestDataset(20), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(splitImageIDs(other), splitImageIDs(splits)) {
+		t.Error("the splits are the same for another seed")
+	}
+}
+
+func TestSplitDatasetStratify(t *testing.T) {
+	dataset := newSplitTestDataset(100, 1, 10)
+	ratios := []float64{0.8, 0.2}
+	splits, err := SplitDataset(dataset, SplitOptions{Names: []string{"train", "val"}, Ratios: ratios, Seed: 1, Stratify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the instances of each category are shared as the ratios within one
+	totals := map[int]int{1: 100, 2: 10}
+	for index, split := range splits {
+		instances := make(map[int]int)
+		for _, annotation := range split.Dataset.Annotations {
+			instances[annotation.CategoryID]++
+		}
+		for categoryID, total := range totals {
+			want := ratios[index] * float64(total)
+			if got := float64(instances[categoryID]); got < want-1 || got > want+1 {
+				t.Errorf("%v has %v instances of category %v, want %v", split.Name, got, categoryID, want)
+			}
+		}
+	}
+}
+
+func TestSplitDatasetGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SplitOptions
+	}{
+		{"pattern", SplitOptions{GroupPattern: regexp.MustCompile(`^(seq\d+)_`)}},
+		{"attribute", SplitOptions{GroupAttribute: "video_id"}},
+		{"stratified pattern", SplitOptions{GroupPattern: regexp.MustCompile(`^seq\d+`), Stratify: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataset := newSplitTestDataset(10, 3, 6)
+			options := test.options
+			options.Names = []string{"train", "val", "test"}
+			options.Ratios = []float64{0.6, 0.2, 0.2}
+			splits, err := SplitDataset(dataset, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the split of every group
+			groupSplits := make(map[string]string)
+			images := 0
+			for _, split := range splits {
+				for _, image := range split.Dataset.Images {
+					group := image.Attributes["video_id"]
+					if name, ok := groupSplits[group]; ok && name != split.Name {
+						t.Errorf("the group %v is in both %v and %v", group, name, split.Name)
+					}
+					groupSplits[group] = split.Name
+					images++
+				}
+			}
+			if images != len(dataset.Images) {
+				t.Errorf("the splits hold %v images, want %v", images, len(dataset.Images))
+			}
+			if len(splits[0].Dataset.Images) != 18 {
+				t.Errorf("train holds %v images, want 6 groups of 3", len(splits[0].Dataset.Images))
+			}
+		})
+	}
+}
+
+func TestSplitDatasetSeed(t *testing.T) {
+	for _, stratify := range []bool{false, true} {
+		options := SplitOptions{Names: []string{"train", "val"}, Ratios: []float64{0.7, 0.3}, Seed: 42, Stratify: stratify}
+		first, err := SplitDataset(newSplitTestDataset(50, 1, 10), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := SplitDataset(newSplitTestDataset(50, 1, 10), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(splitImageIDs(first), splitImageIDs(second)) {
+			t.Errorf("the splits with stratify %v differ for the same seed", stratify)
+		}
+
+		options.Seed = 43
+		other, err := SplitDataset(newSplitTestDataset(50, 1, 10), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reflect.DeepEqual(splitImageIDs(first), splitImageIDs(other)) {
+			t.Errorf("the splits with stratify %v are the same for another seed", stratify)
+		}
+	}
+}
+
+func TestFoldDataset(t *testing.T) {
+	tests := []struct {
+		name    string
+		dataset *Dataset
+		folds   int
+		options SplitOptions
+	}{
+		{"plain", newImagesTestDataset(10), 5, SplitOptions{Seed: 1}},
+		{"uneven", newImagesTestDataset(11), 3, SplitOptions{Seed: 1}},
+		{"stratified", newSplitTestDataset(40, 1, 8), 4, SplitOptions{Seed: 1, Stratify: true}},
+		{"grouped", newSplitTestDataset(10, 3, 6), 5, SplitOptions{Seed: 1, GroupAttribute: "video_id"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folds, err := FoldDataset(test.dataset, test.folds, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(folds) != test.folds {
+				t.Fatalf("got %v folds, want %v", len(folds), test.folds)
+			}
+
+			// the val sets are disjoint and cover the dataset, the train set
+			// of a fold is everything else
+			valFolds := make(map[int]int)
+			for index, fold := range folds {
+				if fold.Index != index+1 {
+					t.Errorf("fold %v has the index %v", index+1, fold.Index)
+				}
+				for _, image := range fold.Val.Images {
+					if other, ok := valFolds[image.ID]; ok {
+						t.Errorf("the image %v is in the val sets of folds %v and %v", image.FileName, other, fold.Index)
+					}
+					valFolds[image.ID] = fold.Index
+				}
+				if len(fold.Train.Images)+len(fold.Val.Images) != len(test.dataset.Images) {
+					t.Errorf("fold %v holds %v train and %v val images, want %v in all", fold.Index, len(fold.Train.Images), len(fold.Val.Images), len(test.dataset.Images))
+				}
+			}
+			if len(valFolds) != len(test.dataset.Images) {
+				t.Errorf("the val sets hold %v images, want %v", len(valFolds), len(test.dataset.Images))
+			}
+			for _, fold := range folds {
+				for _, image := range fold.Train.Images {
+					if valFolds[image.ID] == fold.Index {
+						t.Errorf("the image %v is in both the train and val sets of fold %v", image.FileName, fold.Index)
+					}
+				}
+			}
+
+			if test.options.Stratify {
+				// 8 persons in 4 folds
+				for _, fold := range folds {
+					persons := 0
+					for _, annotation := range fold.Val.Annotations {
+						if annotation.CategoryID == 2 {
+							persons++
+						}
+					}
+					if persons != 2 {
+						t.Errorf("the val set of fold %v holds %v persons, want 2", fold.Index, persons)
+					}
+				}
+			}
+			if test.options.GroupAttribute != "" {
+				groupFolds := make(map[string]int)
+				for _, fold := range folds {
+					for _, image := range fold.Val.Images {
+						group := image.Attributes[test.options.GroupAttribute]
+						if other, ok := groupFolds[group]; ok && other != fold.Index {
+							t.Errorf("the group %v is in the val sets of folds %v and %v", group, other, fold.Index)
+						}
+						groupFolds[group] = fold.Index
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFoldDatasetErrors(t *testing.T) {
+	tests := []struct {
+		folds int
+		err   string
+	}{
+		{1, "at least 2 folds are required"},
+		{4, "can not make 4 folds of 3 images"},
+	}
+	for _, test := range tests {
+		if _, err := FoldDataset(newImagesTestDataset(3), test.folds, SplitOptions{}); err == nil || err.Error() != test.err {
+			t.Errorf("error of %v folds = %v, want %v", test.folds, err, test.err)
+		}
+	}
+}
+
+func TestFoldManifest(t *testing.T) {
+	options := SplitOptions{Seed: 7, Stratify: true, GroupPattern: regexp.MustCompile(`^(seq\d+)_`)}
+	folds, err := FoldDataset(newSplitTestDataset(6, 2, 4), 3, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := NewFoldManifest("a.json", folds, options)
+	path := filepath.Join(t.TempDir(), "folds.json")
+	if err := WriteFoldManifestToFile(&manifest, path); err != nil {
+		t.Fatal(err)
+	}
+
+	manifestBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written struct {
+		Source     string `json:"source"`
+		Folds      int    `json:"folds"`
+		Seed       int64  `json:"seed"`
+		Stratify   bool   `json:"stratify"`
+		GroupRegex string `json:"group_regex"`
+		ValImages  []struct {
+			Fold      int      `json:"fold"`
+			ImageIDs  []int    `json:"image_ids"`
+			FileNames []string `json:"file_names"`
+		} `json:"val_images"`
+	}
+	if err := json.Unmarshal(manifestBytes, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.Source != "a.json" || written.Folds != 3 || written.Seed != 7 || !written.Stratify || written.GroupRegex != `^(seq\d+)_` {
+		t.Errorf("manifest = %s, want the source and options", manifestBytes)
+	}
+	if len(written.ValImages) != 3 {
+		t.Fatalf("the manifest lists %v folds, want 3", len(written.ValImages))
+	}
+	for index, fold := range folds {
+		valImages := written.ValImages[index]
+		if valImages.Fold != fold.Index || len(valImages.ImageIDs) != len(fold.Val.Images) || len(valImages.FileNames) != len(fold.Val.Images) {
+			t.Errorf("the manifest of fold %v = %+v, want its %v val images", fold.Index, valImages, len(fold.Val.Images))
+			continue
+		}
+		for i, image := range fold.Val.Images {
+			if valImages.ImageIDs[i] != image.ID || valImages.FileNames[i] != image.FileName {
+				t.Errorf("val image %v of fold %v = %v %v, want %v %v", i, fold.Index, valImages.ImageIDs[i], valImages.FileNames[i], image.ID, image.FileName)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+)
+
+type CategorySummary struct {
+	ID        int    `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	Instances int    `json:"instances" yaml:"instances"`
+	Images    int    `json:"images" yaml:"images"`
+}
+
+type ImageSizeSummary struct {
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+	Images int `json:"images" yaml:"images"`
+}
+
+type LicenseSummary struct {
+	ID   int    `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+	URL  string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// DatasetSummary is the general info of a dataset
+type DatasetSummary struct {
+	Format      string             `json:"format" yaml:"format"`
+	Path        string             `json:"path" yaml:"path"`
+	Images      int                `json:"images" yaml:"images"`
+	Annotations int                `json:"annotations" yaml:"annotations"`
+	Categories  []CategorySummary  `json:"categories" yaml:"categories"`
+	ImageSizes  []ImageSizeSummary `json:"image_sizes" yaml:"image_sizes"`
+	EmptyImages []string           `json:"empty_images" yaml:"empty_images"`
+
+	// the format-specific metadata, e.g. COCO info and licenses or the VOC source
+	Info     map[string]string `json:"info,omitempty" yaml:"info,omitempty"`
+	Licenses []LicenseSummary  `json:"licenses,omitempty" yaml:"licenses,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// SummarizeDataset counts the images, annotations and categories of the
+// dataset, the image sizes are sorted by the number of images
+func SummarizeDataset(dataset *Dataset) DatasetSummary {
+	summarizer := NewDatasetSummarizer()
+	records := summarizer.Records()
+
+	// the records of a dataset in memory never fail
+	records.Info(dataset.Info)
+	for _, license := range dataset.Licenses {
+		records.License(license)
+	}
+	for _, category := range dataset.Categories {
+		records.Category(category)
+	}
+	for _, image := range dataset.Images {
+		records.Image(image)
+	}
+	for _, annotation := range dataset.Annotations {
+		records.Annotation(annotation)
+	}
+
+	summary := summarizer.Summary()
+	summary.Metadata = dataset.Metadata
+	return summary
+}
+
+// DatasetSummarizer summarizes a dataset from its records in any order, e.g.
+// those of DecodeCOCORecords, it keeps the file names and sizes of the
+// images but not the annotations
+type DatasetSummarizer struct {
+	info       DatasetInfo
+	licenses   []DatasetLicense
+	categories []DatasetCategory
+
+	// the file names and sizes of the images in their order
+	images []DatasetImage
+
+	annotations int
+
+	// the instances of every category ID, and its images
+	instances       map[int]int
+	imageCategories map[[2]int]bool
+	categoryImages  map[int]int
+	annotatedImages map[int]bool
+}
+
+// NewDatasetSummarizer returns a summarizer which has received no record
+func NewDatasetSummarizer() *DatasetSummarizer {
+	return &DatasetSummarizer{
+		instances:       make(map[int]int),
+		imageCategories: make(map[[2]int]bool),
+		categoryImages:  make(map[int]int),
+		annotatedImages: make(map[int]bool),
+	}
+}
+
+// Records returns the functions receiving the records of the dataset
+func (summarizer *DatasetSummarizer) Records() DatasetRecords {
+	return DatasetRecords{
+		Info: func(info DatasetInfo) error {
+			summarizer.info = info
+			return nil
+		},
+		License: func(license DatasetLicense) error {
+			summarizer.licenses = append(summarizer.licenses, license)
+			return nil
+		},
+		Category: func(category DatasetCategory) error {
+			summarizer.categories = append(summarizer.categories, category)
+			return nil
+		},
+		Image: func(image DatasetImage) error {
+			summarizer.images = append(summarizer.images, DatasetImage{
+				ID:       image.ID,
+				FileName: image.FileName,
+				Width:    image.Width,
+				Height:   image.Height,
+			})
+			return nil
+		},
+		Annotation: func(annotation DatasetAnnotation) error {
+			summarizer.annotations++
+			summarizer.annotatedImages[annotation.ImageID] = true
+			summarizer.instances[annotation.CategoryID]++
+			imageCategory := [2]int{annotation.ImageID, annotation.CategoryID}
+			if !summarizer.imageCategories[imageCategory] {
+				summarizer.imageCategories[imageCategory] = true
+				summarizer.categoryImages[annotation.CategoryID]++
+			}
+			return nil
+		},
+	}
+}
+
+// Summary returns the summary of the records received so far
+func (summarizer *DatasetSummarizer) Summary() DatasetSummary {
+	summary := DatasetSummary{
+		Images:      len(summarizer.images),
+		Annotations: summarizer.annotations,
+		Categories:  make([]CategorySummary, len(summarizer.categories)),
+		ImageSizes:  make([]ImageSizeSummary, 0),
+		EmptyImages: make([]string, 0),
+		Info:        make(map[string]string),
+		Licenses:    make([]LicenseSummary, len(summarizer.licenses)),
+	}
+
+	// the annotations of a category ID used twice count for the last one
+	categoryIndexes := make(map[int]int, len(summarizer.categories))
+	for index, category := range summarizer.categories {
+		summary.Categories[index] = CategorySummary{
+			ID:   category.ID,
+			Name: category.Name,
+		}
+		categoryIndexes[category.ID] = index
+	}
+	for categoryID, index := range categoryIndexes {
+		summary.Categories[index].Instances = summarizer.instances[categoryID]
+		summary.Categories[index].Images = summarizer.categoryImages[categoryID]
+	}
+
+	sizeIndexes := make(map[[2]int]int)
+	for _, image := range summarizer.images {
+		size := [2]int{image.Width, image.Height}
+		if index, ok := sizeIndexes[size]; ok {
+			summary.ImageSizes[index].Images++
+		} else {
+			sizeIndexes[size] = len(summary.ImageSizes)
+			summary.ImageSizes = append(summary.ImageSizes, ImageSizeSummary{
+				Width:  image.Width,
+				Height: image.Height,
+				Images: 1,
+			})
+		}
+
+		if !summarizer.annotatedImages[image.ID] {
+			summary.EmptyImages = append(summary.EmptyImages, image.FileName)
+		}
+	}
+	sort.SliceStable(summary.ImageSizes, func(i, j int) bool {
+		return summary.ImageSizes[i].Images > summary.ImageSizes[j].Images
+	})
+
+	year := ""
+	if summarizer.info.Year != 0 {
+		year = strconv.Itoa(summarizer.info.Year)
+	}
+	info := map[string]string{
+		"year":         year,
+		"version":      summarizer.info.Version,
+		"description":  summarizer.info.Description,
+		"contributor":  summarizer.info.Contributor,
+		"url":          summarizer.info.URL,
+		"date_created": summarizer.info.DateCreated,
+	}
+	for key, value := range info {
+		if value != "" {
+			summary.Info[key] = value
+		}
+	}
+
+	for index, license := range summarizer.licenses {
+		summary.Licenses[index] = LicenseSummary{
+			ID:   license.ID,
+			Name: license.Name,
+			URL:  license.URL,
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeDataset(t *testing.T) {
+	tests := []struct {
+		name    string
+		read    func(dataset *Dataset, path string) error
+		path    string
+		summary DatasetSummary
+	}{
+		{
+			name: "coco",
+			read: ReadDatasetFromCOCOFile,
+			path: filepath.Join("testdata", "coco.json"),
+			summary: DatasetSummary{
+				Images:      2,
+				Annotations: 3,
+				Categories:  []CategorySummary{{ID: 1, Name: "car", Instances: 1, Images: 1}, {ID: 2, Name: "dog", Instances: 2, Images: 2}},
+				ImageSizes:  []ImageSizeSummary{{Width: 100, Height: 80, Images: 1}, {Width: 110, Height: 85, Images: 1}},
+				EmptyImages: []string{},
+				Info: map[string]string{
+					"year":         "2022",
+					"version":      "1",
+					"description":  "datasetgo test data",
+					"date_created": "2022-01-01T00:00:00+00:00",
+				},
+				Licenses: []LicenseSummary{{ID: 1, Name: "test"}},
+			},
+		},
+		{
+			name: "voc",
+			read: ReadDatasetFromPascalVOCDir,
+			path: filepath.Join("testdata", "voc"),
+			summary: DatasetSummary{
+				Images:      2,
+				Annotations: 3,
+				Categories:  []CategorySummary{{ID: 1, Name: "car", Instances: 1, Images: 1}, {ID: 2, Name: "dog", Instances: 2, Images: 2}},
+				ImageSizes:  []ImageSizeSummary{{Width: 100, Height: 80, Images: 1}, {Width: 110, Height: 85, Images: 1}},
+				EmptyImages: []string{},
+				Info:        map[string]string{},
+				Licenses:    []LicenseSummary{},
+				Metadata:    map[string]string{"database": "datasetgo"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var dataset Dataset
+			if err := test.read(&dataset, test.path); err != nil {
+				t.Fatal(err)
+			}
+			if summary := SummarizeDataset(&dataset); !reflect.DeepEqual(summary, test.summary) {
+				t.Errorf("summary = %+v, want %+v", summary, test.summary)
+			}
+		})
+	}
+}
+
+func TestSummarizeDatasetCounts(t *testing.T) {
+	// two dogs in a.jpg, b.jpg of the same size is empty and c.jpg holds an
+	// annotation of a category which does not exist
+	dataset := Dataset{
+		Categories: []DatasetCategory{{ID: 1, Name: "car"}, {ID: 2, Name: "dog"}},
+		Images: []DatasetImage{
+			{ID: 1, FileName: "a.jpg", Width: 100, Height: 80},
+			{ID: 2, FileName: "b.jpg", Width: 100, Height: 80},
+			{ID: 3, FileName: "c.jpg", Width: 640, Height: 480},
+		},
+		Annotations: []DatasetAnnotation{
+			{ID: 1, ImageID: 1, CategoryID: 2},
+			{ID: 2, ImageID: 1, CategoryID: 2},
+			{ID: 3, ImageID: 3, CategoryID: 9},
+		},
+	}
+	summary := SummarizeDataset(&dataset)
+
+	if summary.Images != 3 || summary.Annotations != 3 {
+		t.Errorf("summary of %v images and %v annotations, want 3 and 3", summary.Images, summary.Annotations)
+	}
+	categories := []CategorySummary{{ID: 1, Name: "car"}, {ID: 2, Name: "dog", Instances: 2, Images: 1}}
+	if !reflect.DeepEqual(summary.Categories, categories) {
+		t.Errorf("categories = %+v, want %+v", summary.Categories, categories)
+	}
+	// the sizes are sorted by the number of images
+	sizes := []ImageSizeSummary{{Width: 100, Height: 80, Images: 2}, {Width: 640, Height: 480, Images: 1}}
+	if !reflect.DeepEqual(summary.ImageSizes, sizes) {
+		t.Errorf("image sizes = %+v, want %+v", summary.ImageSizes, sizes)
+	}
+	if !reflect.DeepEqual(summary.EmptyImages, []string{"b.jpg"}) {
+		t.Errorf("empty images = %v, want b.jpg", summary.EmptyImages)
+	}
+}
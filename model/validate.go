@@ -0,0 +1,402 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// the checks of the validation
+const (
+	CheckDuplicateID       = "duplicate-id"
+	CheckDuplicateFile     = "duplicate-file"
+	CheckDanglingImage     = "dangling-image"
+	CheckDanglingCategory  = "dangling-category"
+	CheckInvalidNumber     = "invalid-number"
+	CheckInvertedBox       = "inverted-box"
+	CheckZeroArea          = "zero-area"
+	CheckOutOfBounds       = "out-of-bounds"
+	CheckMissingSize       = "missing-size"
+	CheckSizeMismatch      = "size-mismatch"
+	CheckMissingImage      = "missing-image"
+	CheckUnreadableImage   = "unreadable-image"
+	CheckEmptyImage        = "empty-image"
+	CheckUnusedCategory    = "unused-category"
+	CheckDuplicateCategory = "duplicate-category"
+)
+
+type ValidationIssue struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	Check    string   `json:"check" yaml:"check"`
+	Message  string   `json:"message" yaml:"message"`
+
+	// the image, annotation or category the issue is about, if any
+	Image        string `json:"image,omitempty" yaml:"image,omitempty"`
+	ImageID      int    `json:"image_id,omitempty" yaml:"image_id,omitempty"`
+	AnnotationID int    `json:"annotation_id,omitempty" yaml:"annotation_id,omitempty"`
+	CategoryID   int    `json:"category_id,omitempty" yaml:"category_id,omitempty"`
+}
+
+// ValidationReport is the issues of a dataset in the order they were found
+type ValidationReport struct {
+	Format      string            `json:"format" yaml:"format"`
+	Path        string            `json:"path" yaml:"path"`
+	Images      int               `json:"images" yaml:"images"`
+	Annotations int               `json:"annotations" yaml:"annotations"`
+	Errors      int               `json:"errors" yaml:"errors"`
+	Warnings    int               `json:"warnings" yaml:"warnings"`
+	Infos       int               `json:"infos" yaml:"infos"`
+	Issues      []ValidationIssue `json:"issues" yaml:"issues"`
+}
+
+type ValidationOptions struct {
+	// check the image files exist and their headers agree with the sizes
+	CheckImages bool
+}
+
+func (report *ValidationReport) add(issue ValidationIssue) {
+	switch issue.Severity {
+	case SeverityError:
+		report.Errors++
+	case SeverityWarning:
+		report.Warnings++
+	default:
+		report.Infos++
+	}
+	report.Issues = append(report.Issues, issue)
+}
+
+// ValidateDataset checks the references, IDs, boxes and image sizes of the
+// dataset, with the image files when options.CheckImages
+func ValidateDataset(dataset *Dataset, options ValidationOptions) ValidationReport {
+	validator := NewDatasetValidator(dataset, options)
+	records := validator.Records()
+
+	// the records of a dataset in memory never fail
+	for _, category := range dataset.Categories {
+		records.Category(category)
+	}
+	for _, image := range dataset.Images {
+		records.Image(image)
+	}
+	for _, annotation := range dataset.Annotations {
+		records.Annotation(annotation)
+	}
+	return validator.Report()
+}
+
+// DatasetValidator validates a dataset from its records, e.g. those of
+// DecodeCOCORecords. It keeps the categories, the file names and sizes of the
+// images and the IDs of the annotations, but not the annotations, so all the
+// categories and images must come before the annotations: a file holding them
+// in any order is decoded twice, first for the categories and images.
+type DatasetValidator struct {
+	// the ImageDir and ImageFS of the dataset locate the image files
+	dataset *Dataset
+	options ValidationOptions
+	report  ValidationReport
+
+	categories    []DatasetCategory
+	categoryIDs   map[int]bool
+	categoryNames map[string]bool
+
+	// the images in their order, those not checked yet follow checkedImages
+	images        []DatasetImage
+	checkedImages int
+	imageIndexes  map[int]int
+	fileNames     map[string]bool
+
+	annotationIDs   map[int]bool
+	annotatedImages map[int]bool
+	usedCategories  map[int]bool
+}
+
+// NewDatasetValidator returns a validator which has received no record, the
+// image files are located by the ImageDir and ImageFS of the dataset
+func NewDatasetValidator(dataset *Dataset, options ValidationOptions) *DatasetValidator {
+	return &DatasetValidator{
+		dataset:         &Dataset{ImageDir: dataset.ImageDir, ImageFS: dataset.ImageFS},
+		options:         options,
+		report:          ValidationReport{Issues: make([]ValidationIssue, 0)},
+		categoryIDs:     make(map[int]bool),
+		categoryNames:   make(map[string]bool),
+		imageIndexes:    make(map[int]int),
+		fileNames:       make(map[string]bool),
+		annotationIDs:   make(map[int]bool),
+		annotatedImages: make(map[int]bool),
+		usedCategories:  make(map[int]bool),
+	}
+}
+
+// Records returns the functions receiving the records of the dataset, the
+// categories and images fail after the first annotation
+func (validator *DatasetValidator) Records() DatasetRecords {
+	return DatasetRecords{
+		Category: func(category DatasetCategory) error {
+			if validator.report.Annotations > 0 {
+				return errors.New("the categories must come before the annotations to validate")
+			}
+			validator.addCategory(category)
+			return nil
+		},
+		Image: func(image DatasetImage) error {
+			if validator.report.Annotations > 0 {
+				return errors.New("the images must come before the annotations to validate")
+			}
+			// the images are checked in their order before the annotations
+			validator.images = append(validator.images, DatasetImage{
+				ID:       image.ID,
+				FileName: image.FileName,
+				Path:     image.Path,
+				Width:    image.Width,
+				Height:   image.Height,
+			})
+			return nil
+		},
+		Annotation: func(annotation DatasetAnnotation) error {
+			validator.checkImages()
+			validator.addAnnotation(annotation)
+			return nil
+		},
+	}
+}
+
+// Report returns the report of the records received so far, with the images
+// and categories which have no annotation
+func (validator *DatasetValidator) Report() ValidationReport {
+	validator.checkImages()
+
+	report := validator.report
+	report.Images = len(validator.images)
+	report.Issues = append(make([]ValidationIssue, 0, len(validator.report.Issues)), validator.report.Issues...)
+	for _, image := range validator.images {
+		if !validator.annotatedImages[image.ID] {
+			report.add(ValidationIssue{
+				Severity: SeverityInfo,
+				Check:    CheckEmptyImage,
+				Message:  fmt.Sprintf("the image [%v] has no annotation", image.FileName),
+				Image:    image.FileName,
+				ImageID:  image.ID,
+			})
+		}
+	}
+	for _, category := range validator.categories {
+		if !validator.usedCategories[category.ID] {
+			report.add(ValidationIssue{
+				Severity:   SeverityInfo,
+				Check:      CheckUnusedCategory,
+				Message:    fmt.Sprintf("the category [%v] has no annotation", category.Name),
+				CategoryID: category.ID,
+			})
+		}
+	}
+
+	return report
+}
+
+func (validator *DatasetValidator) addCategory(category DatasetCategory) {
+	report := &validator.report
+	if validator.categoryIDs[category.ID] {
+		report.add(ValidationIssue{
+			Severity:   SeverityError,
+			Check:      CheckDuplicateID,
+			Message:    fmt.Sprintf("the category ID[%v] is used more than once", category.ID),
+			CategoryID: category.ID,
+		})
+	}
+	if validator.categoryNames[category.Name] {
+		report.add(ValidationIssue{
+			Severity:   SeverityWarning,
+			Check:      CheckDuplicateCategory,
+			Message:    fmt.Sprintf("the category name [%v] is used more than once", category.Name),
+			CategoryID: category.ID,
+		})
+	}
+	validator.categories = append(validator.categories, DatasetCategory{ID: category.ID, Name: category.Name})
+	validator.categoryIDs[category.ID] = true
+	validator.categoryNames[category.Name] = true
+}
+
+// checkImages checks the images received since the last check
+func (validator *DatasetValidator) checkImages() {
+	report := &validator.report
+	var files []imageFile
+	if validator.options.CheckImages {
+		files = readImageFiles(validator.dataset, validator.images[validator.checkedImages:])
+	}
+	for index := validator.checkedImages; index < len(validator.images); index++ {
+		image := validator.images[index]
+		if _, ok := validator.imageIndexes[image.ID]; ok {
+			report.add(ValidationIssue{
+				Severity: SeverityError,
+				Check:    CheckDuplicateID,
+				Message:  fmt.Sprintf("the image ID[%v] is used more than once", image.ID),
+				Image:    image.FileName,
+				ImageID:  image.ID,
+			})
+		} else {
+			validator.imageIndexes[image.ID] = index
+		}
+		if validator.fileNames[image.FileName] {
+			report.add(ValidationIssue{
+				Severity: SeverityWarning,
+				Check:    CheckDuplicateFile,
+				Message:  fmt.Sprintf("the image file [%v] is listed more than once", image.FileName),
+				Image:    image.FileName,
+				ImageID:  image.ID,
+			})
+		}
+		validator.fileNames[image.FileName] = true
+
+		var file *imageFile
+		if files != nil {
+			file = &files[index-validator.checkedImages]
+		}
+		validateImage(report, validator.dataset, image, file)
+	}
+	validator.checkedImages = len(validator.images)
+}
+
+func (validator *DatasetValidator) addAnnotation(annotation DatasetAnnotation) {
+	report := &validator.report
+	report.Annotations++
+	if validator.annotationIDs[annotation.ID] {
+		report.add(ValidationIssue{
+			Severity:     SeverityError,
+			Check:        CheckDuplicateID,
+			Message:      fmt.Sprintf("the annotation ID[%v] is used more than once", annotation.ID),
+			AnnotationID: annotation.ID,
+		})
+	}
+	validator.annotationIDs[annotation.ID] = true
+	validator.annotatedImages[annotation.ImageID] = true
+	validator.usedCategories[annotation.CategoryID] = true
+
+	if !validator.categoryIDs[annotation.CategoryID] {
+		report.add(ValidationIssue{
+			Severity:     SeverityError,
+			Check:        CheckDanglingCategory,
+			Message:      fmt.Sprintf("the category with ID[%v] does not exist(annotation with ID[%v])", annotation.CategoryID, annotation.ID),
+			ImageID:      annotation.ImageID,
+			AnnotationID: annotation.ID,
+			CategoryID:   annotation.CategoryID,
+		})
+	}
+
+	var image DatasetImage
+	index, ok := validator.imageIndexes[annotation.ImageID]
+	if ok {
+		image = validator.images[index]
+	} else {
+		report.add(ValidationIssue{
+			Severity:     SeverityError,
+			Check:        CheckDanglingImage,
+			Message:      fmt.Sprintf("the image with ID[%v] does not exist(annotation with ID[%v])", annotation.ImageID, annotation.ID),
+			ImageID:      annotation.ImageID,
+			AnnotationID: annotation.ID,
+		})
+	}
+	validateBox(report, annotation, image, ok)
+}
+
+// validateImage checks the size of the image, against what its file tells
+// when the image files are checked
+func validateImage(report *ValidationReport, dataset *Dataset, image DatasetImage, file *imageFile) {
+	issue := ValidationIssue{
+		Severity: SeverityError,
+		Image:    image.FileName,
+		ImageID:  image.ID,
+	}
+
+	if image.Width <= 0 || image.Height <= 0 {
+		issue.Check = CheckMissingSize
+		issue.Message = fmt.Sprintf("the size %vx%v of image [%v] is invalid", image.Width, image.Height, image.FileName)
+		report.add(issue)
+	}
+	if file == nil {
+		return
+	}
+
+	if file.statErr != nil {
+		issue.Check = CheckMissingImage
+		issue.Message = fmt.Sprintf("the image file [%v] does not exist", dataset.ImagePath(&image))
+		report.add(issue)
+		return
+	}
+	if file.err != nil {
+		issue.Check = CheckUnreadableImage
+		issue.Message = file.err.Error()
+		report.add(issue)
+		return
+	}
+	if image.Width > 0 && image.Height > 0 && (file.width != image.Width || file.height != image.Height) {
+		issue.Check = CheckSizeMismatch
+		issue.Message = fmt.Sprintf("the size %vx%v of image [%v] disagrees with its file of %vx%v", image.Width, image.Height, image.FileName, file.width, file.height)
+		report.add(issue)
+	}
+}
+
+// validateBox checks the box of the annotation, against the image size when
+// the image is known
+func validateBox(report *ValidationReport, annotation DatasetAnnotation, image DatasetImage, imageKnown bool) {
+	box := annotation.BBox
+	issue := ValidationIssue{
+		Severity:     SeverityError,
+		Image:        image.FileName,
+		ImageID:      annotation.ImageID,
+		AnnotationID: annotation.ID,
+	}
+
+	for _, value := range []float64{box.X, box.Y, box.Width, box.Height, annotation.Area} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			issue.Check = CheckInvalidNumber
+			issue.Message = fmt.Sprintf("the box %v of annotation with ID[%v] has an invalid number", formatBox(box), annotation.ID)
+			report.add(issue)
+			return
+		}
+	}
+
+	if box.Width < 0 || box.Height < 0 {
+		issue.Check = CheckInvertedBox
+		issue.Message = fmt.Sprintf("the box %v of annotation with ID[%v] has its min after its max", formatBox(box), annotation.ID)
+		report.add(issue)
+		return
+	}
+	if box.Width == 0 || box.Height == 0 {
+		issue.Check = CheckZeroArea
+		issue.Message = fmt.Sprintf("the box %v of annotation with ID[%v] has zero area", formatBox(box), annotation.ID)
+		report.add(issue)
+		return
+	}
+
+	if !imageKnown || image.Width <= 0 || image.Height <= 0 {
+		return
+	}
+	width, height := float64(image.Width), float64(image.Height)
+	if box.X >= 0 && box.Y >= 0 && box.X+box.Width <= width && box.Y+box.Height <= height {
+		return
+	}
+	issue.Check = CheckOutOfBounds
+	// a box partly outside the image is usually a rounding or labeling slip
+	// which clipping fixes, one entirely outside has nothing left to keep
+	if box.X >= width || box.Y >= height || box.X+box.Width <= 0 || box.Y+box.Height <= 0 {
+		issue.Message = fmt.Sprintf("the box %v of annotation with ID[%v] is outside the image of %vx%v", formatBox(box), annotation.ID, image.Width, image.Height)
+	} else {
+		issue.Severity = SeverityWarning
+		issue.Message = fmt.Sprintf("the box %v of annotation with ID[%v] exceeds the image of %vx%v", formatBox(box), annotation.ID, image.Width, image.Height)
+	}
+	report.add(issue)
+}
+
+// formatBox formats the box as [xmin, ymin, xmax, ymax]
+func formatBox(box BoundingBox) string {
+	return fmt.Sprintf("[%g, %g, %g, %g]", box.X, box.Y, box.X+box.Width, box.Y+box.Height)
+}
@@ -0,0 +1,215 @@
+package model
+
+import (
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newValidateTestDataset returns a valid dataset of a.jpg in the voc testdata
+// holding a car
+func newValidateTestDataset() *Dataset {
+	return &Dataset{
+		Categories:  []DatasetCategory{{ID: 1, Name: "car"}},
+		Images:      []DatasetImage{{ID: 1, FileName: "a.jpg", Width: 100, Height: 80}},
+		Annotations: []DatasetAnnotation{{ID: 1, ImageID: 1, CategoryID: 1, BBox: BoundingBox{X: 10, Y: 10, Width: 20, Height: 20}, Area: 400}},
+		ImageDir:    filepath.Join("testdata", "voc"),
+	}
+}
+
+func TestValidateDataset(t *testing.T) {
+	type issue struct {
+		severity Severity
+		check    string
+	}
+	tests := []struct {
+		name        string
+		modify      func(t *testing.T, dataset *Dataset)
+		checkImages bool
+		issues      []issue
+	}{
+		{
+			name:        "valid",
+			modify:      func(t *testing.T, dataset *Dataset) {},
+			checkImages: true,
+		},
+		{
+			name: "duplicate category ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Categories = append(dataset.Categories, DatasetCategory{ID: 1, Name: "truck"})
+			},
+			issues: []issue{{SeverityError, CheckDuplicateID}},
+		},
+		{
+			name: "duplicate category name",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Categories = append(dataset.Categories, DatasetCategory{ID: 2, Name: "car"})
+			},
+			issues: []issue{{SeverityWarning, CheckDuplicateCategory}, {SeverityInfo, CheckUnusedCategory}},
+		},
+		{
+			name: "duplicate image ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images = append(dataset.Images, DatasetImage{ID: 1, FileName: "b.jpg", Width: 110, Height: 85})
+			},
+			issues: []issue{{SeverityError, CheckDuplicateID}},
+		},
+		{
+			name: "duplicate file",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images = append(dataset.Images, DatasetImage{ID: 2, FileName: "a.jpg", Width: 100, Height: 80})
+			},
+			issues: []issue{{SeverityWarning, CheckDuplicateFile}, {SeverityInfo, CheckEmptyImage}},
+		},
+		{
+			name: "duplicate annotation ID",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations = append(dataset.Annotations, dataset.Annotations[0])
+			},
+			issues: []issue{{SeverityError, CheckDuplicateID}},
+		},
+		{
+			name: "dangling category",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].CategoryID = 9
+			},
+			issues: []issue{{SeverityError, CheckDanglingCategory}, {SeverityInfo, CheckUnusedCategory}},
+		},
+		{
+			name: "dangling image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].ImageID = 9
+			},
+			issues: []issue{{SeverityError, CheckDanglingImage}, {SeverityInfo, CheckEmptyImage}},
+		},
+		{
+			name: "invalid number",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.X = math.NaN()
+			},
+			issues: []issue{{SeverityError, CheckInvalidNumber}},
+		},
+		{
+			name: "inverted box",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.Width = -5
+			},
+			issues: []issue{{SeverityError, CheckInvertedBox}},
+		},
+		{
+			name: "zero area",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox.Height = 0
+			},
+			issues: []issue{{SeverityError, CheckZeroArea}},
+		},
+		{
+			name: "box exceeding the image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: 90, Y: 70, Width: 20, Height: 20}
+			},
+			issues: []issue{{SeverityWarning, CheckOutOfBounds}},
+		},
+		{
+			name: "box outside the image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Annotations[0].BBox = BoundingBox{X: 120, Y: 10, Width: 10, Height: 10}
+			},
+			issues: []issue{{SeverityError, CheckOutOfBounds}},
+		},
+		{
+			name: "missing size",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images[0].Width = 0
+			},
+			issues: []issue{{SeverityError, CheckMissingSize}},
+		},
+		{
+			name: "missing image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.ImageDir = t.TempDir()
+			},
+			checkImages: true,
+			issues:      []issue{{SeverityError, CheckMissingImage}},
+		},
+		{
+			name: "unreadable image",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.ImageDir = t.TempDir()
+				if err := ioutil.WriteFile(filepath.Join(dataset.ImageDir, "a.jpg"), []byte("not an image"), 0666); err != nil {
+					t.Fatal(err)
+				}
+			},
+			checkImages: true,
+			issues:      []issue{{SeverityError, CheckUnreadableImage}},
+		},
+		{
+			name: "size mismatch",
+			modify: func(t *testing.T, dataset *Dataset) {
+				dataset.Images[0].Width, dataset.Images[0].Height = 50, 50
+			},
+			checkImages: true,
+			issues:      []issue{{SeverityError, CheckSizeMismatch}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dataset := newValidateTestDataset()
+			test.modify(t, dataset)
+			report := ValidateDataset(dataset, ValidationOptions{CheckImages: test.checkImages})
+
+			issues := make([]issue, 0)
+			for _, reported := range report.Issues {
+				issues = append(issues, issue{reported.Severity, reported.Check})
+			}
+			if test.issues == nil {
+				test.issues = []issue{}
+			}
+			if !reflect.DeepEqual(issues, test.issues) {
+				t.Errorf("issues = %+v, want %+v", report.Issues, test.issues)
+			}
+		})
+	}
+}
+
+func TestDatasetValidatorRecords(t *testing.T) {
+	dataset := newValidateTestDataset()
+	dataset.Images = append(dataset.Images, DatasetImage{ID: 2, FileName: "missing.jpg"})
+	dataset.Annotations = append(dataset.Annotations, DatasetAnnotation{ID: 1, ImageID: 3, CategoryID: 2, BBox: BoundingBox{Width: 10, Height: 10}})
+	options := ValidationOptions{CheckImages: true}
+	want := ValidateDataset(dataset, options)
+
+	validator := NewDatasetValidator(dataset, options)
+	records := validator.Records()
+	for _, image := range dataset.Images {
+		if err := records.Image(image); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, category := range dataset.Categories {
+		if err := records.Category(category); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, annotation := range dataset.Annotations {
+		if err := records.Annotation(annotation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := validator.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("report by records = %+v, want %+v", got, want)
+	}
+	// the report is the same when asked again
+	if got := validator.Report(); !reflect.DeepEqual(got, want) {
+		t.Errorf("second report by records = %+v, want %+v", got, want)
+	}
+
+	if err := records.Image(DatasetImage{ID: 4, FileName: "d.jpg"}); err == nil {
+		t.Error("an image after the annotations is received without error")
+	}
+	if err := records.Category(DatasetCategory{ID: 4, Name: "dog"}); err == nil {
+		t.Error("a category after the annotations is received without error")
+	}
+}
@@ -0,0 +1,576 @@
+package model
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	MustRegisterFormat(&Format{
+		Name:        "yolo",
+		Aliases:     []string{"darknet", "ultralytics"},
+		Description: "YOLO(Darknet/Ultralytics) txt",
+		Detect:      detectFromOS(detectYOLODir),
+		Reader:      DatasetReaderFunc(ReadDatasetFromYOLODir),
+		Writer:      yoloWriter{},
+		DetectFS:    detectYOLODir,
+		FSReader:    DatasetFSReaderFunc(ReadDatasetFromYOLOFS),
+	})
+}
+
+// the file extensions of the images in a yolo dataset
+var yoloImageExts = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".bmp":  true,
+	".gif":  true,
+	".tif":  true,
+	".tiff": true,
+	".webp": true,
+}
+
+// YOLOLabel is a line of the label file, the values are normalized by the
+// image size and (X, Y) is the center of the box
+type YOLOLabel struct {
+	Class   int
+	X       float64
+	Y       float64
+	Width   float64
+	Height  float64
+	Polygon []float64
+}
+
+type YOLOAnnotation struct {
+	// the image path relative to the images directory
+	Image  string
+	Width  int
+	Height int
+	Labels []YOLOLabel
+}
+
+type YOLOAnnotations struct {
+	Names       []string
+	Annotations []YOLOAnnotation
+
+	// the directory the image paths are relative to
+	ImageDir string
+}
+
+type yoloDataConfig struct {
+	Path  string      `yaml:"path,omitempty"`
+	Train string      `yaml:"train"`
+	Val   string      `yaml:"val"`
+	NC    int         `yaml:"nc"`
+	Names interface{} `yaml:"names"`
+}
+
+// yoloDirs returns the images and labels directories of the yolo dataset, the
+// images and labels are side by side in the darknet flat layout
+func yoloDirs(fsys fs.FS, dir string) (string, string) {
+	imageDir := path.Join(dir, "images")
+	labelDir := path.Join(dir, "labels")
+	if isDir(fsys, imageDir) && isDir(fsys, labelDir) {
+		return imageDir, labelDir
+	}
+	return dir, dir
+}
+
+func isDir(fsys fs.FS, name string) bool {
+	fileInfo, err := fs.Stat(fsys, name)
+	return err == nil && fileInfo.IsDir()
+}
+
+// ReadYOLONames reads the class names from data.yaml of the dataset, or from
+// classes.txt when there is no data.yaml or it has no names
+func ReadYOLONames(names *[]string, path string) error {
+	return readYOLONamesFS(names, osDirFS(path), ".")
+}
+
+func readYOLONamesFS(names *[]string, fsys fs.FS, dir string) error {
+	for _, fileName := range []string{"data.yaml", "data.yml"} {
+		yamlBytes, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+
+		var config yoloDataConfig
+		if err := yaml.Unmarshal(yamlBytes, &config); err != nil {
+			return fmt.Errorf("%v reading... %v", fileName, err.Error())
+		}
+		// a data.yaml of the splits only leaves the names to classes.txt
+		if config.Names != nil {
+			return parseYOLONames(names, config.Names)
+		}
+		break
+	}
+
+	for _, fileName := range []string{"classes.txt", "obj.names"} {
+		txtBytes, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+
+		*names = make([]string, 0)
+		for _, line := range strings.Split(string(txtBytes), "\n") {
+			if name := strings.TrimSpace(line); name != "" {
+				*names = append(*names, name)
+			}
+		}
+		return nil
+	}
+
+	return errors.New("not found classes.txt or the names of data.yaml in the directory path")
+}
+
+// parseYOLONames accepts both the list and the index map form of names
+func parseYOLONames(names *[]string, value interface{}) error {
+	switch value := value.(type) {
+	case []interface{}:
+		*names = make([]string, len(value))
+		for index, name := range value {
+			(*names)[index] = fmt.Sprint(name)
+		}
+
+	case map[string]interface{}:
+		indexedNames := make(map[interface{}]interface{}, len(value))
+		for key, name := range value {
+			indexedNames[key] = name
+		}
+		return parseYOLONames(names, indexedNames)
+
+	case map[interface{}]interface{}:
+		*names = make([]string, len(value))
+		for key, name := range value {
+			index, err := strconv.Atoi(fmt.Sprint(key))
+			if err != nil || index < 0 || index >= len(value) {
+				return fmt.Errorf("the class index %v in data.yaml is invalid", key)
+			}
+			(*names)[index] = fmt.Sprint(name)
+		}
+
+	default:
+		return errors.New("not found names in data.yaml")
+	}
+
+	return nil
+}
+
+// ReadYOLOLabelsFromFile reads the labels of an image from the txt file
+func ReadYOLOLabelsFromFile(labels *[]YOLOLabel, path string) error {
+	fsys, name := osPathFS(path)
+	return readYOLOLabelsFS(labels, fsys, name)
+}
+
+func readYOLOLabelsFS(labels *[]YOLOLabel, fsys fs.FS, name string) error {
+	txtFile, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer txtFile.Close()
+
+	// the os path of the file in the messages
+	path := fsPath(fsys, name)
+	scanner := bufio.NewScanner(txtFile)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 5 || (len(fields) > 5 && len(fields)%2 == 0) {
+			return fmt.Errorf("%v:%v: a label needs a class and 4 box values or polygon points", path, lineNumber)
+		}
+
+		class, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("%v:%v: %v", path, lineNumber, err.Error())
+		}
+		values := make([]float64, len(fields)-1)
+		for index, field := range fields[1:] {
+			if values[index], err = strconv.ParseFloat(field, 64); err != nil {
+				return fmt.Errorf("%v:%v: %v", path, lineNumber, err.Error())
+			}
+		}
+
+		label := YOLOLabel{Class: class}
+		if len(values) == 4 {
+			label.X, label.Y, label.Width, label.Height = values[0], values[1], values[2], values[3]
+		} else {
+			// the segment format, the box is the extent of the polygon
+			label.Polygon = values
+			minX, minY, maxX, maxY := polygonExtent(values)
+			label.X, label.Y = (minX+maxX)/2, (minY+maxY)/2
+			label.Width, label.Height = maxX-minX, maxY-minY
+		}
+		*labels = append(*labels, label)
+	}
+
+	return scanner.Err()
+}
+
+// ReadYOLOAnnotationsFromDir reads the yolo dataset from the directory, in
+// either the images/labels layout or the flat darknet layout
+func ReadYOLOAnnotationsFromDir(annotations *YOLOAnnotations, path string) error {
+	fsys := osDirFS(path)
+	if err := ReadYOLOAnnotationsFS(annotations, fsys, "."); err != nil {
+		return err
+	}
+	annotations.ImageDir = fsys.path(annotations.ImageDir)
+	return nil
+}
+
+// ReadYOLOAnnotationsFS is like ReadYOLOAnnotationsFromDir but reads the
+// directory of the file system, the ImageDir of the annotations is in it
+func ReadYOLOAnnotationsFS(annotations *YOLOAnnotations, fsys fs.FS, dir string) error {
+	var names []string
+	if err := readYOLONamesFS(&names, fsys, dir); err != nil {
+		return err
+	}
+
+	imageDir, labelDir := yoloDirs(fsys, dir)
+	*annotations = YOLOAnnotations{
+		Names:       names,
+		Annotations: make([]YOLOAnnotation, 0),
+		ImageDir:    imageDir,
+	}
+
+	imagePaths := make([]string, 0)
+	err := fs.WalkDir(fsys, imageDir, func(imagePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && yoloImageExts[strings.ToLower(path.Ext(imagePath))] {
+			imagePaths = append(imagePaths, imagePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the images and labels are read with LoadJobs workers in the walk order
+	loaded := make([]YOLOAnnotation, len(imagePaths))
+	err = loadParallel(len(imagePaths), func(index int) error {
+		imagePath := imagePaths[index]
+		relPath := strings.TrimPrefix(imagePath, imageDir+"/")
+		if imageDir == "." {
+			relPath = imagePath
+		}
+		width, height, err := ReadImageSizeFS(fsys, imagePath)
+		if err != nil {
+			return err
+		}
+
+		annotation := YOLOAnnotation{
+			Image:  relPath,
+			Width:  width,
+			Height: height,
+			Labels: make([]YOLOLabel, 0),
+		}
+
+		// an image without label file has no objects
+		labelPath := path.Join(labelDir, strings.TrimSuffix(relPath, path.Ext(relPath))+".txt")
+		if _, err := fs.Stat(fsys, labelPath); err == nil {
+			if err := readYOLOLabelsFS(&annotation.Labels, fsys, labelPath); err != nil {
+				return err
+			}
+		}
+
+		loaded[index] = annotation
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	annotations.Annotations = loaded
+
+	if len(annotations.Annotations) == 0 {
+		return errors.New("not found image file in the directory path")
+	}
+
+	return nil
+}
+
+// DecodeYOLOAnnotations decodes the yolo annotations data into the dataset,
+// the class index i becomes the category with ID i+1
+func DecodeYOLOAnnotations(dataset *Dataset, annotations *YOLOAnnotations) error {
+	*dataset = Dataset{
+		Categories:  make([]DatasetCategory, len(annotations.Names)),
+		Images:      make([]DatasetImage, 0, len(annotations.Annotations)),
+		Annotations: make([]DatasetAnnotation, 0),
+		ImageDir:    annotations.ImageDir,
+	}
+
+	for index, name := range annotations.Names {
+		dataset.Categories[index] = DatasetCategory{
+			ID:   index + 1,
+			Name: name,
+		}
+	}
+
+	for _, yoloAnnotation := range annotations.Annotations {
+		imageID := dataset.appendImage(DatasetImage{
+			FileName: yoloAnnotation.Image,
+			Width:    yoloAnnotation.Width,
+			Height:   yoloAnnotation.Height,
+		})
+
+		width := float64(yoloAnnotation.Width)
+		height := float64(yoloAnnotation.Height)
+		for _, label := range yoloAnnotation.Labels {
+			if label.Class < 0 || label.Class >= len(annotations.Names) {
+				return fmt.Errorf("the class %v of image [%v] is not in the class names", label.Class, yoloAnnotation.Image)
+			}
+
+			segmentation := make([]Polygon, 0)
+			if len(label.Polygon) > 0 {
+				polygon := make(Polygon, len(label.Polygon))
+				for index, value := range label.Polygon {
+					if index%2 == 0 {
+						polygon[index] = value * width
+					} else {
+						polygon[index] = value * height
+					}
+				}
+				segmentation = append(segmentation, polygon)
+			}
+
+			box := BoundingBox{
+				X:      (label.X - label.Width/2) * width,
+				Y:      (label.Y - label.Height/2) * height,
+				Width:  label.Width * width,
+				Height: label.Height * height,
+			}
+			dataset.appendAnnotation(DatasetAnnotation{
+				ImageID:      imageID,
+				CategoryID:   label.Class + 1,
+				BBox:         box,
+				Area:         box.Width * box.Height,
+				Segmentation: segmentation,
+			})
+		}
+	}
+
+	return nil
+}
+
+// EncodeYOLOAnnotations encodes the dataset into the yolo annotations data,
+// the classes follow the order of the dataset categories
+func EncodeYOLOAnnotations(annotations *YOLOAnnotations, dataset *Dataset) error {
+	classMap := make(map[int]int, len(dataset.Categories))
+	names := make([]string, len(dataset.Categories))
+	for index, category := range dataset.Categories {
+		classMap[category.ID] = index
+		names[index] = category.Name
+	}
+
+	imageMap := dataset.ImageMap()
+	for _, annotation := range dataset.Annotations {
+		if _, ok := imageMap[annotation.ImageID]; !ok {
+			return &DanglingReferenceError{Kind: ReferenceImage, ID: annotation.ImageID, AnnotationID: annotation.ID}
+		}
+	}
+
+	*annotations = YOLOAnnotations{
+		Names:       names,
+		Annotations: make([]YOLOAnnotation, 0, len(dataset.Images)),
+		ImageDir:    dataset.ImageDir,
+	}
+
+	annotationMap := dataset.AnnotationsByImage()
+	for _, image := range dataset.Images {
+		width, height := image.Width, image.Height
+		if width <= 0 || height <= 0 {
+			var err error
+			if width, height, err = dataset.ReadImageSize(&image); err != nil {
+				return err
+			}
+		}
+
+		yoloAnnotation := YOLOAnnotation{
+			Image:  image.FileName,
+			Width:  width,
+			Height: height,
+			Labels: make([]YOLOLabel, 0),
+		}
+
+		for _, annotation := range annotationMap[image.ID] {
+			class, ok := classMap[annotation.CategoryID]
+			if !ok {
+				return &DanglingReferenceError{Kind: ReferenceCategory, ID: annotation.CategoryID, AnnotationID: annotation.ID}
+			}
+
+			label := YOLOLabel{
+				Class:  class,
+				X:      (annotation.BBox.X + annotation.BBox.Width/2) / float64(width),
+				Y:      (annotation.BBox.Y + annotation.BBox.Height/2) / float64(height),
+				Width:  annotation.BBox.Width / float64(width),
+				Height: annotation.BBox.Height / float64(height),
+			}
+			// only a single polygon fits in the segment format
+			if len(annotation.Segmentation) == 1 {
+				polygon := annotation.Segmentation[0]
+				label.Polygon = make([]float64, len(polygon))
+				for index, value := range polygon {
+					if index%2 == 0 {
+						label.Polygon[index] = value / float64(width)
+					} else {
+						label.Polygon[index] = value / float64(height)
+					}
+				}
+			}
+			yoloAnnotation.Labels = append(yoloAnnotation.Labels, label)
+		}
+
+		annotations.Annotations = append(annotations.Annotations, yoloAnnotation)
+	}
+
+	return nil
+}
+
+// WriteYOLOAnnotationsToDir writes the label files under labels/ of the
+// directory together with classes.txt and data.yaml
+func WriteYOLOAnnotationsToDir(annotations *YOLOAnnotations, path string) error {
+	labelDir := filepath.Join(path, "labels")
+	if err := os.MkdirAll(labelDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for _, annotation := range annotations.Annotations {
+		imageExt := filepath.Ext(annotation.Image)
+		labelPath := filepath.Join(labelDir, filepath.FromSlash(strings.TrimSuffix(annotation.Image, imageExt)+".txt"))
+		if err := os.MkdirAll(filepath.Dir(labelPath), os.ModePerm); err != nil {
+			return err
+		}
+
+		var builder strings.Builder
+		for _, label := range annotation.Labels {
+			if len(label.Polygon) >= 6 {
+				builder.WriteString(strconv.Itoa(label.Class))
+				for _, value := range label.Polygon {
+					builder.WriteString(fmt.Sprintf(" %.6f", value))
+				}
+				builder.WriteString("\n")
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("%d %.6f %.6f %.6f %.6f\n", label.Class, label.X, label.Y, label.Width, label.Height))
+		}
+		if err := ioutil.WriteFile(labelPath, []byte(builder.String()), 0666); err != nil {
+			return err
+		}
+	}
+
+	classes := strings.Join(annotations.Names, "\n")
+	if len(annotations.Names) > 0 {
+		classes += "\n"
+	}
+	if err := ioutil.WriteFile(filepath.Join(path, "classes.txt"), []byte(classes), 0666); err != nil {
+		return err
+	}
+
+	config := yoloDataConfig{
+		Train: "images",
+		Val:   "images",
+		NC:    len(annotations.Names),
+		Names: annotations.Names,
+	}
+	yamlBytes, err := yaml.Marshal(&config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(path, "data.yaml"), yamlBytes, 0666)
+}
+
+// ReadDatasetFromYOLODir reads the dataset from the yolo dataset directory
+func ReadDatasetFromYOLODir(dataset *Dataset, path string) error {
+	return readDatasetFromOS(dataset, path, ReadDatasetFromYOLOFS)
+}
+
+// ReadDatasetFromYOLOFS reads the yolo dataset from the directory of the file system
+func ReadDatasetFromYOLOFS(dataset *Dataset, fsys fs.FS, dir string) error {
+	var annotations YOLOAnnotations
+	if err := ReadYOLOAnnotationsFS(&annotations, fsys, dir); err != nil {
+		return err
+	}
+
+	if err := DecodeYOLOAnnotations(dataset, &annotations); err != nil {
+		return err
+	}
+	dataset.ImageFS = fsys
+	return nil
+}
+
+// WriteDatasetToYOLODir writes the dataset to the directory as a yolo dataset
+func WriteDatasetToYOLODir(dataset *Dataset, path string) error {
+	var annotations YOLOAnnotations
+	if err := EncodeYOLOAnnotations(&annotations, dataset); err != nil {
+		return err
+	}
+
+	return WriteYOLOAnnotationsToDir(&annotations, path)
+}
+
+// yoloWriter writes yolo dataset directories, the images are placed in
+// images/ beside labels/
+type yoloWriter struct{}
+
+func (yoloWriter) WriteDataset(dataset *Dataset, path string) error {
+	return WriteDatasetToYOLODir(dataset, path)
+}
+
+func (yoloWriter) ImageDir(path string) string {
+	return filepath.Join(path, "images")
+}
+
+func (yoloWriter) WriteDatasetLayout(dataset *Dataset, path string) error {
+	return WriteDatasetToYOLODir(dataset, path)
+}
+
+// detectYOLODir reports whether the dir is a directory with the class names
+// and either a labels directory or label txt files
+func detectYOLODir(fsys fs.FS, dir string) bool {
+	var names []string
+	if !isDir(fsys, dir) || readYOLONamesFS(&names, fsys, dir) != nil {
+		return false
+	}
+
+	if _, labelDir := yoloDirs(fsys, dir); labelDir != dir {
+		return true
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if strings.ToLower(path.Ext(fileName)) == ".txt" && fileName != "classes.txt" {
+			return true
+		}
+	}
+	return false
+}
+
+// polygonExtent returns the min and max coordinates of the flat point list
+func polygonExtent(points []float64) (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for index := 0; index+1 < len(points); index += 2 {
+		minX = math.Min(minX, points[index])
+		maxX = math.Max(maxX, points[index])
+		minY = math.Min(minY, points[index+1])
+		maxY = math.Max(maxY, points[index+1])
+	}
+	return minX, minY, maxX, maxY
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestYOLORoundTrip(t *testing.T) {
+	// the category IDs are not the class indexes and b.jpg has no objects
+	source := Dataset{
+		Categories: []DatasetCategory{{ID: 3, Name: "car"}, {ID: 7, Name: "person"}},
+		Images: []DatasetImage{
+			{ID: 1, FileName: "a.jpg", Width: 100, Height: 80},
+			{ID: 2, FileName: "b.jpg", Width: 110, Height: 85},
+		},
+		Annotations: []DatasetAnnotation{
+			{ID: 1, ImageID: 1, CategoryID: 3, BBox: BoundingBox{X: 10, Y: 20, Width: 30, Height: 40}},
+			{ID: 2, ImageID: 1, CategoryID: 7, BBox: BoundingBox{X: 50, Y: 40, Width: 20, Height: 20}, Segmentation: []Polygon{{50, 40, 70, 40, 70, 60}}},
+		},
+	}
+	path := t.TempDir()
+	if err := WriteDatasetToYOLODir(&source, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range source.Images {
+		writeTestJPEG(t, filepath.Join(path, "images", image.FileName), image.Width, image.Height)
+	}
+
+	files := map[string]string{
+		"classes.txt":  "car\nperson\n",
+		"labels/a.txt": "0 0.250000 0.500000 0.300000 0.500000\n1 0.500000 0.500000 0.700000 0.500000 0.700000 0.750000\n",
+		"labels/b.txt": "",
+	}
+	for name, want := range files {
+		got, err := ioutil.ReadFile(filepath.Join(path, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%v = %q, want %q", name, got, want)
+		}
+	}
+
+	var dataset Dataset
+	if err := ReadDatasetFromYOLODir(&dataset, path); err != nil {
+		t.Fatal(err)
+	}
+	if len(dataset.Categories) != 2 || dataset.Categories[0] != (DatasetCategory{ID: 1, Name: "car"}) || dataset.Categories[1] != (DatasetCategory{ID: 2, Name: "person"}) {
+		t.Errorf("categories = %+v, want car and person with the IDs 1 and 2", dataset.Categories)
+	}
+	if len(dataset.Images) != 2 || len(dataset.Annotations) != 2 {
+		t.Fatalf("read %v images and %v annotations, want 2 and 2", len(dataset.Images), len(dataset.Annotations))
+	}
+	for index, image := range dataset.Images {
+		want := source.Images[index]
+		if image.FileName != want.FileName || image.Width != want.Width || image.Height != want.Height {
+			t.Errorf("image = %+v, want %+v", image, want)
+		}
+	}
+
+	categories := map[int]int{3: 1, 7: 2}
+	for index, annotation := range dataset.Annotations {
+		want := source.Annotations[index]
+		if annotation.ImageID != dataset.Images[0].ID || annotation.CategoryID != categories[want.CategoryID] {
+			t.Errorf("annotation %v is of image %v and category %v, want a.jpg and %v", index, annotation.ImageID, annotation.CategoryID, categories[want.CategoryID])
+		}
+		if !closeBBox(annotation.BBox, want.BBox) {
+			t.Errorf("bbox = %+v, want %+v", annotation.BBox, want.BBox)
+		}
+		if len(annotation.Segmentation) != len(want.Segmentation) {
+			t.Errorf("segmentation = %v, want %v", annotation.Segmentation, want.Segmentation)
+		}
+	}
+}
+
+func closeBBox(a BoundingBox, b BoundingBox) bool {
+	const epsilon = 1e-3
+	return math.Abs(a.X-b.X) < epsilon && math.Abs(a.Y-b.Y) < epsilon &&
+		math.Abs(a.Width-b.Width) < epsilon && math.Abs(a.Height-b.Height) < epsilon
+}
+
+// writeTestJPEG writes a gray jpeg of the size to the path
+func writeTestJPEG(t *testing.T, path string, width int, height int) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	jpegFile, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jpegFile.Close()
+	if err := jpeg.Encode(jpegFile, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadYOLONames(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		names []string
+		err   string
+	}{
+		{"data.yaml", map[string]string{"data.yaml": "names: [car, person]", "classes.txt": "dog\n"}, []string{"car", "person"}, ""},
+		{"data.yml of indexes", map[string]string{"data.yml": "names: {1: person, 0: car}"}, []string{"car", "person"}, ""},
+		{"data.yaml without names", map[string]string{"data.yaml": "train: images/train\nval: images/val", "classes.txt": "car\n\nperson\n"}, []string{"car", "person"}, ""},
+		{"obj.names", map[string]string{"obj.names": "car\nperson"}, []string{"car", "person"}, ""},
+		{"data.yaml without names alone", map[string]string{"data.yaml": "train: images/train"}, nil, "not found classes.txt or the names of data.yaml"},
+		{"no names", map[string]string{"a.txt": ""}, nil, "not found classes.txt or the names of data.yaml"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for name, content := range test.files {
+				fsys["yolo/"+name] = &fstest.MapFile{Data: []byte(content)}
+			}
+
+			var names []string
+			err := readYOLONamesFS(&names, fsys, "yolo")
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, test.names) {
+				t.Errorf("names = %v, want %v", names, test.names)
+			}
+		})
+	}
+}